@@ -7,6 +7,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/kpetremann/salt-exporter/internal/metrics"
 	"github.com/kpetremann/salt-exporter/pkg/listener"
@@ -19,6 +20,13 @@ const defaultPort = 2112
 const defaultHealthMinion = true
 const defaultHealthFunctionsFilter = "state.highstate"
 const defaultHealthStatesFilter = "highstate"
+const defaultJobTimeout = 15 * time.Minute
+const defaultSlowestStates = 5
+const defaultEventQueueSize = 1000
+
+var defaultJobLatencyBuckets = []float64{0.5, 1, 2.5, 5, 10, 30, 60, 120, 300, 600, 1800}
+var defaultJobDurationBuckets = []float64{1, 5, 10, 30, 60, 120, 300, 600, 1200, 1800, 3600}
+var defaultStateDurationBuckets = []float64{0.1, 0.5, 1, 5, 10, 30, 60, 120, 300, 600}
 
 var flagConfigMapping = map[string]string{
 	"host":                    "listen-address",
@@ -26,6 +34,7 @@ var flagConfigMapping = map[string]string{
 	"tls":                     "tls.enabled",
 	"tls-cert":                "tls.certificate",
 	"tls-key":                 "tls.key",
+	"web-config-file":         "web-config-file",
 	"ignore-test":             "metrics.global.filters.ignore-test",
 	"ignore-mock":             "metrics.global.filters.ignore-mock",
 	"health-minions":          "metrics.health-minions",
@@ -33,18 +42,42 @@ var flagConfigMapping = map[string]string{
 	"health-states-filter":    "metrics.salt_function_status.filters.states",
 }
 
+type SaltAPI struct {
+	URL   string `mapstructure:"url"`
+	Token string `mapstructure:"token"`
+}
+
+// Source is an event source, i.e. one of the salt-masters of a multi-master setup.
+type Source struct {
+	Name    string
+	IPCFile string  `mapstructure:"ipc-file"`
+	PKIDir  string  `mapstructure:"pki-dir"`
+	SaltAPI SaltAPI `mapstructure:"salt-api"`
+}
+
 type Config struct {
 	LogLevel string `mapstructure:"log-level"`
 
-	ListenAddress string `mapstructure:"listen-address"`
-	ListenPort    int    `mapstructure:"listen-port"`
-	IPCFile       string `mapstructure:"ipc-file"`
-	PKIDir        string `mapstructure:"pki-dir"`
+	ListenAddress string  `mapstructure:"listen-address"`
+	ListenPort    int     `mapstructure:"listen-port"`
+	IPCFile       string  `mapstructure:"ipc-file"`
+	PKIDir        string  `mapstructure:"pki-dir"`
+	SaltAPI       SaltAPI `mapstructure:"salt-api"`
+	Sources       []Source
 	TLS           struct {
 		Enabled     bool
 		Key         string
 		Certificate string
 	}
+	WebConfigFile string `mapstructure:"web-config-file"`
+	Readiness     struct {
+		MaxEventAge time.Duration `mapstructure:"max-event-age"`
+	}
+	Reconnect  listener.Backoff
+	EventQueue struct {
+		Size           int
+		OverflowPolicy listener.OverflowPolicy `mapstructure:"overflow-policy"`
+	} `mapstructure:"event-queue"`
 
 	Metrics metrics.Config
 }
@@ -56,10 +89,11 @@ func parseFlags() (string, bool) {
 	flag.String("log-level", defaultLogLevel, "log level (debug, info, warn, error, fatal, panic, disabled)")
 	flag.String("host", "", "listen address")
 	flag.Int("port", defaultPort, "listen port")
-	flag.String("ipc-file", listener.DefaultIPCFilepath, "file location of the salt-master event bus")
+	flag.String("ipc-file", listener.DefaultIPCFilepath, "file location of the salt-master event bus, or tcp://host:port if ipc_mode is tcp")
 	flag.Bool("tls", false, "enable TLS")
 	flag.String("tls-cert", "", "TLS certificated")
 	flag.String("tls-key", "", "TLS private key")
+	flag.String("web-config-file", "", "web configuration file (exporter-toolkit format) for TLS and basic auth")
 
 	flag.Bool("ignore-test", false, "ignore test=True events")
 	flag.Bool("ignore-mock", false, "ignore mock=True events")
@@ -90,7 +124,15 @@ func setDefaults(healthMinions bool) {
 	viper.SetDefault("listen-port", defaultPort)
 	viper.SetDefault("ipc-file", listener.DefaultIPCFilepath)
 	viper.SetDefault("pki-dir", listener.DefaultPKIDirpath)
+	viper.SetDefault("reconnect.initial-interval", listener.DefaultBackoff.InitialInterval)
+	viper.SetDefault("reconnect.max-interval", listener.DefaultBackoff.MaxInterval)
+	viper.SetDefault("reconnect.multiplier", listener.DefaultBackoff.Multiplier)
+	viper.SetDefault("reconnect.jitter", listener.DefaultBackoff.Jitter)
+	viper.SetDefault("reconnect.max-retries", listener.DefaultBackoff.MaxRetries)
+	viper.SetDefault("event-queue.size", defaultEventQueueSize)
+	viper.SetDefault("event-queue.overflow-policy", listener.OverflowBlock)
 	viper.SetDefault("metrics.health-minions", defaultHealthMinion)
+	viper.SetDefault("metrics.handlers", []string{metrics.BuiltinHandler})
 	viper.SetDefault("metrics.salt_new_job_total.enabled", true)
 	viper.SetDefault("metrics.salt_expected_responses_total.enabled", true)
 	viper.SetDefault("metrics.salt_function_responses_total.enabled", true)
@@ -98,8 +140,27 @@ func setDefaults(healthMinions bool) {
 	viper.SetDefault("metrics.salt_function_status.enabled", healthMinions) // TODO: true once health-minions will be removed
 	viper.SetDefault("metrics.salt_responses_total.enabled", healthMinions) // TODO: true once health-minions will be removed
 	viper.SetDefault("metrics.salt_job_duration_seconds.enabled", true)
+	viper.SetDefault("metrics.salt_orchestrate_step_result.enabled", true)
+	viper.SetDefault("metrics.salt_orchestrate_step_duration_seconds.enabled", true)
+	viper.SetDefault("metrics.salt_orchestrate_duration_seconds.enabled", true)
+	viper.SetDefault("metrics.salt_wheel_function_total.enabled", true)
+	viper.SetDefault("metrics.salt_reactor_executions_total.enabled", true)
+	viper.SetDefault("metrics.salt_key_events_total.enabled", true)
+	viper.SetDefault("metrics.salt_keys.enabled", false)
+	viper.SetDefault("metrics.salt_minion_auth_total.enabled", true)
+	viper.SetDefault("metrics.salt_minion_start_total.enabled", true)
+	viper.SetDefault("metrics.salt_minion_last_start.enabled", false)
+	viper.SetDefault("metrics.salt_minion_connected.enabled", true)
+	viper.SetDefault("metrics.salt_minion_connect_total.enabled", true)
+	viper.SetDefault("metrics.salt_minion_disconnect_total.enabled", true)
+	viper.SetDefault("metrics.salt_job_duration_seconds.type", metrics.GaugeType)
+	viper.SetDefault("metrics.salt_job_duration_seconds.buckets", defaultJobDurationBuckets)
 	viper.SetDefault("metrics.salt_function_status.filters.functions", []string{defaultHealthFunctionsFilter})
 	viper.SetDefault("metrics.salt_function_status.filters.states", []string{defaultHealthStatesFilter})
+	viper.SetDefault("metrics.salt_state_duration_seconds.buckets", defaultStateDurationBuckets)
+	viper.SetDefault("metrics.salt_state_slowest_duration_seconds.top", defaultSlowestStates)
+	viper.SetDefault("metrics.salt_job_missing_responses_total.timeout", defaultJobTimeout)
+	viper.SetDefault("metrics.salt_job_response_latency_seconds.buckets", defaultJobLatencyBuckets)
 }
 
 func getConfig(configFileName string, healthMinions bool) (Config, error) {
@@ -159,9 +220,110 @@ func checkRequirements(cfg Config) error {
 		}
 	}
 
+	names := make(map[string]bool)
+	for _, source := range cfg.EventSources() {
+		if len(cfg.Sources) > 0 {
+			if source.Name == "" {
+				return errors.New("source name not specified")
+			}
+			if names[source.Name] {
+				return fmt.Errorf("duplicate source name: %s", source.Name)
+			}
+			names[source.Name] = true
+		}
+
+		if err := checkSaltAPI(source.SaltAPI); err != nil {
+			return err
+		}
+	}
+
+	switch cfg.Metrics.SaltJobDurationSeconds.Type {
+	case metrics.GaugeType, metrics.HistogramType, metrics.NativeHistogramType:
+	default:
+		return fmt.Errorf("invalid salt_job_duration_seconds type: %s", cfg.Metrics.SaltJobDurationSeconds.Type)
+	}
+
+	if err := cfg.Reconnect.Validate(); err != nil {
+		return fmt.Errorf("invalid reconnect settings: %w", err)
+	}
+
+	if cfg.EventQueue.Size < 0 {
+		return errors.New("event-queue size must be positive")
+	}
+	if err := cfg.EventQueue.OverflowPolicy.Validate(); err != nil {
+		return err
+	}
+	if cfg.EventQueue.OverflowPolicy != listener.OverflowBlock && cfg.EventQueue.Size == 0 {
+		return fmt.Errorf("event-queue size required with the %s overflow policy", cfg.EventQueue.OverflowPolicy)
+	}
+
+	if cfg.Readiness.MaxEventAge < 0 {
+		return errors.New("readiness max-event-age must be positive")
+	}
+
+	if cfg.Metrics.Global.MaxSeriesPerMetric < 0 {
+		return errors.New("max-series-per-metric must be positive")
+	}
+
+	for _, customEvent := range cfg.Metrics.CustomEvents {
+		if err := metrics.ValidateCustomEvent(customEvent); err != nil {
+			return err
+		}
+	}
+
+	for _, beacon := range cfg.Metrics.Beacons {
+		if err := metrics.ValidateBeaconMetric(beacon); err != nil {
+			return err
+		}
+	}
+
+	jobTrackingEnabled := cfg.Metrics.SaltJobMissingResponsesTotal.Enabled || cfg.Metrics.SaltJobResponseLatencySeconds.Enabled
+	if jobTrackingEnabled && cfg.Metrics.SaltJobMissingResponsesTotal.Timeout <= 0 {
+		return errors.New("salt_job_missing_responses_total timeout must be greater than 0")
+	}
+
+	if cfg.Metrics.SaltStateSlowestDurationSeconds.Enabled && cfg.Metrics.SaltStateSlowestDurationSeconds.Top < 1 {
+		return errors.New("salt_state_slowest_duration_seconds top must be greater than 0")
+	}
+
 	return nil
 }
 
+func checkSaltAPI(saltAPI SaltAPI) error {
+	if saltAPI.URL == "" {
+		return nil
+	}
+	if !strings.HasPrefix(saltAPI.URL, "http://") && !strings.HasPrefix(saltAPI.URL, "https://") {
+		return fmt.Errorf("invalid salt-api url: %s", saltAPI.URL)
+	}
+	if saltAPI.Token == "" {
+		return errors.New("salt-api token not specified")
+	}
+	return nil
+}
+
+// EventSources returns the salt-masters to listen to.
+//
+// Without sources, the top level settings are used for a single unnamed source.
+func (c Config) EventSources() []Source {
+	if len(c.Sources) == 0 {
+		return []Source{{IPCFile: c.IPCFile, PKIDir: c.PKIDir, SaltAPI: c.SaltAPI}}
+	}
+
+	sources := make([]Source, 0, len(c.Sources))
+	for _, source := range c.Sources {
+		if source.IPCFile == "" {
+			source.IPCFile = listener.DefaultIPCFilepath
+		}
+		if source.PKIDir == "" {
+			source.PKIDir = listener.DefaultPKIDirpath
+		}
+		sources = append(sources, source)
+	}
+
+	return sources
+}
+
 func ReadConfig() (Config, error) {
 	var err error
 
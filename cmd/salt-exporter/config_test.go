@@ -4,6 +4,7 @@ import (
 	"flag"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/kpetremann/salt-exporter/internal/metrics"
@@ -38,6 +39,19 @@ func TestReadConfigFlagOnly(t *testing.T) {
 					Key:         "",
 					Certificate: "",
 				},
+				Readiness: struct {
+					MaxEventAge time.Duration `mapstructure:"max-event-age"`
+				}{
+					MaxEventAge: 0,
+				},
+				Reconnect: listener.DefaultBackoff,
+				EventQueue: struct {
+					Size           int
+					OverflowPolicy listener.OverflowPolicy `mapstructure:"overflow-policy"`
+				}{
+					Size:           defaultEventQueueSize,
+					OverflowPolicy: listener.OverflowBlock,
+				},
 				Metrics: metrics.Config{
 					HealthMinions: true,
 					Global: struct {
@@ -45,6 +59,8 @@ func TestReadConfigFlagOnly(t *testing.T) {
 							IgnoreTest bool `mapstructure:"ignore-test"`
 							IgnoreMock bool `mapstructure:"ignore-mock"`
 						}
+						MinionSeriesTTL    time.Duration `mapstructure:"minion-series-ttl"`
+						MaxSeriesPerMetric int           `mapstructure:"max-series-per-metric"`
 					}{
 						Filters: struct {
 							IgnoreTest bool `mapstructure:"ignore-test"`
@@ -54,6 +70,7 @@ func TestReadConfigFlagOnly(t *testing.T) {
 							IgnoreMock: false,
 						},
 					},
+					Handlers: []string{"builtin"},
 					SaltNewJobTotal: struct{ Enabled bool }{
 						Enabled: true,
 					},
@@ -100,9 +117,100 @@ func TestReadConfigFlagOnly(t *testing.T) {
 					SaltJobDurationSeconds: struct {
 						Enabled        bool
 						AddMinionLabel bool `mapstructure:"add-minion-label"`
+						Type           string
+						Buckets        []float64
 					}{
 						Enabled:        true,
 						AddMinionLabel: false,
+						Type:           "gauge",
+						Buckets:        []float64{1, 5, 10, 30, 60, 120, 300, 600, 1200, 1800, 3600},
+					},
+					SaltStateDurationSeconds: struct {
+						Enabled        bool
+						AddMinionLabel bool `mapstructure:"add-minion-label"`
+						Buckets        []float64
+					}{
+						Enabled:        false,
+						AddMinionLabel: false,
+						Buckets:        []float64{0.1, 0.5, 1, 5, 10, 30, 60, 120, 300, 600},
+					},
+					SaltStateSlowestDurationSeconds: struct {
+						Enabled        bool
+						AddMinionLabel bool `mapstructure:"add-minion-label"`
+						Top            int
+					}{
+						Enabled:        false,
+						AddMinionLabel: false,
+						Top:            5,
+					},
+					SaltOrchestrateStepResult: struct{ Enabled bool }{
+						Enabled: true,
+					},
+					SaltOrchestrateStepDurationSeconds: struct{ Enabled bool }{
+						Enabled: true,
+					},
+					SaltOrchestrateDurationSeconds: struct{ Enabled bool }{
+						Enabled: true,
+					},
+					SaltWheelFunctionTotal: struct{ Enabled bool }{
+						Enabled: true,
+					},
+					SaltReactorExecutionsTotal: struct{ Enabled bool }{
+						Enabled: true,
+					},
+					SaltKeyEventsTotal: struct{ Enabled bool }{
+						Enabled: true,
+					},
+					SaltKeys: struct{ Enabled bool }{
+						Enabled: false,
+					},
+					SaltMinionAuthTotal: struct {
+						Enabled        bool
+						AddMinionLabel bool `mapstructure:"add-minion-label"`
+					}{
+						Enabled: true,
+					},
+					SaltMinionStartTotal: struct {
+						Enabled        bool
+						AddMinionLabel bool `mapstructure:"add-minion-label"`
+					}{
+						Enabled: true,
+					},
+					SaltMinionLastStart: struct{ Enabled bool }{
+						Enabled: false,
+					},
+					SaltMinionConnected: struct{ Enabled bool }{
+						Enabled: true,
+					},
+					SaltMinionConnectTotal: struct {
+						Enabled        bool
+						AddMinionLabel bool `mapstructure:"add-minion-label"`
+					}{
+						Enabled:        true,
+						AddMinionLabel: false,
+					},
+					SaltMinionDisconnectTotal: struct {
+						Enabled        bool
+						AddMinionLabel bool `mapstructure:"add-minion-label"`
+					}{
+						Enabled:        true,
+						AddMinionLabel: false,
+					},
+					SaltJobMissingResponsesTotal: struct {
+						Enabled bool
+						Timeout time.Duration
+					}{
+						Enabled: false,
+						Timeout: 15 * time.Minute,
+					},
+					SaltJobResponseLatencySeconds: struct {
+						Enabled        bool
+						AddMinionLabel bool `mapstructure:"add-minion-label"`
+						Buckets        []float64
+					}{
+						Enabled:        false,
+						AddMinionLabel: false,
+						Buckets:        []float64{0.5, 1, 2.5, 5, 10, 30, 60, 120, 300, 600, 1800},
 					},
 				},
 			},
@@ -137,6 +245,19 @@ func TestReadConfigFlagOnly(t *testing.T) {
 					Key:         "./key",
 					Certificate: "./cert",
 				},
+				Readiness: struct {
+					MaxEventAge time.Duration `mapstructure:"max-event-age"`
+				}{
+					MaxEventAge: 0,
+				},
+				Reconnect: listener.DefaultBackoff,
+				EventQueue: struct {
+					Size           int
+					OverflowPolicy listener.OverflowPolicy `mapstructure:"overflow-policy"`
+				}{
+					Size:           defaultEventQueueSize,
+					OverflowPolicy: listener.OverflowBlock,
+				},
 				Metrics: metrics.Config{
 					HealthMinions: false,
 					Global: struct {
@@ -144,6 +265,8 @@ func TestReadConfigFlagOnly(t *testing.T) {
 							IgnoreTest bool `mapstructure:"ignore-test"`
 							IgnoreMock bool `mapstructure:"ignore-mock"`
 						}
+						MinionSeriesTTL    time.Duration `mapstructure:"minion-series-ttl"`
+						MaxSeriesPerMetric int           `mapstructure:"max-series-per-metric"`
 					}{
 						Filters: struct {
 							IgnoreTest bool `mapstructure:"ignore-test"`
@@ -153,6 +276,7 @@ func TestReadConfigFlagOnly(t *testing.T) {
 							IgnoreMock: true,
 						},
 					},
+					Handlers: []string{"builtin"},
 					SaltNewJobTotal: struct{ Enabled bool }{
 						Enabled: true,
 					},
@@ -199,10 +323,101 @@ func TestReadConfigFlagOnly(t *testing.T) {
 					SaltJobDurationSeconds: struct {
 						Enabled        bool
 						AddMinionLabel bool `mapstructure:"add-minion-label"`
+						Type           string
+						Buckets        []float64
+					}{
+						Enabled:        true,
+						AddMinionLabel: false,
+						Type:           "gauge",
+						Buckets:        []float64{1, 5, 10, 30, 60, 120, 300, 600, 1200, 1800, 3600},
+					},
+					SaltStateDurationSeconds: struct {
+						Enabled        bool
+						AddMinionLabel bool `mapstructure:"add-minion-label"`
+						Buckets        []float64
+					}{
+						Enabled:        false,
+						AddMinionLabel: false,
+						Buckets:        []float64{0.1, 0.5, 1, 5, 10, 30, 60, 120, 300, 600},
+					},
+					SaltStateSlowestDurationSeconds: struct {
+						Enabled        bool
+						AddMinionLabel bool `mapstructure:"add-minion-label"`
+						Top            int
+					}{
+						Enabled:        false,
+						AddMinionLabel: false,
+						Top:            5,
+					},
+					SaltOrchestrateStepResult: struct{ Enabled bool }{
+						Enabled: true,
+					},
+					SaltOrchestrateStepDurationSeconds: struct{ Enabled bool }{
+						Enabled: true,
+					},
+					SaltOrchestrateDurationSeconds: struct{ Enabled bool }{
+						Enabled: true,
+					},
+					SaltWheelFunctionTotal: struct{ Enabled bool }{
+						Enabled: true,
+					},
+					SaltReactorExecutionsTotal: struct{ Enabled bool }{
+						Enabled: true,
+					},
+					SaltKeyEventsTotal: struct{ Enabled bool }{
+						Enabled: true,
+					},
+					SaltKeys: struct{ Enabled bool }{
+						Enabled: false,
+					},
+					SaltMinionAuthTotal: struct {
+						Enabled        bool
+						AddMinionLabel bool `mapstructure:"add-minion-label"`
+					}{
+						Enabled: true,
+					},
+					SaltMinionStartTotal: struct {
+						Enabled        bool
+						AddMinionLabel bool `mapstructure:"add-minion-label"`
+					}{
+						Enabled: true,
+					},
+					SaltMinionLastStart: struct{ Enabled bool }{
+						Enabled: false,
+					},
+					SaltMinionConnected: struct{ Enabled bool }{
+						Enabled: true,
+					},
+					SaltMinionConnectTotal: struct {
+						Enabled        bool
+						AddMinionLabel bool `mapstructure:"add-minion-label"`
 					}{
 						Enabled:        true,
 						AddMinionLabel: false,
 					},
+					SaltMinionDisconnectTotal: struct {
+						Enabled        bool
+						AddMinionLabel bool `mapstructure:"add-minion-label"`
+					}{
+						Enabled:        true,
+						AddMinionLabel: false,
+					},
+					SaltJobMissingResponsesTotal: struct {
+						Enabled bool
+						Timeout time.Duration
+					}{
+						Enabled: false,
+						Timeout: 15 * time.Minute,
+					},
+					SaltJobResponseLatencySeconds: struct {
+						Enabled        bool
+						AddMinionLabel bool `mapstructure:"add-minion-label"`
+						Buckets        []float64
+					}{
+						Enabled:        false,
+						AddMinionLabel: false,
+						Buckets:        []float64{0.5, 1, 2.5, 5, 10, 30, 60, 120, 300, 600, 1800},
+					},
 				},
 			},
 		},
@@ -236,6 +451,76 @@ func TestReadConfigFlagOnly(t *testing.T) {
 	}
 }
 
+func TestEventSources(t *testing.T) {
+	single := Config{IPCFile: "/dev/null", PKIDir: "/tmp/pki"}
+	want := []Source{{IPCFile: "/dev/null", PKIDir: "/tmp/pki"}}
+	if diff := cmp.Diff(want, single.EventSources()); diff != "" {
+		t.Errorf("Mismatch for single source (-want +got):\n%s", diff)
+	}
+
+	multi := Config{
+		IPCFile: "/dev/null",
+		Sources: []Source{{Name: "master1"}, {Name: "master2", IPCFile: "tcp://master2:4512", PKIDir: "/tmp/pki"}},
+	}
+	want = []Source{
+		{Name: "master1", IPCFile: listener.DefaultIPCFilepath, PKIDir: listener.DefaultPKIDirpath},
+		{Name: "master2", IPCFile: "tcp://master2:4512", PKIDir: "/tmp/pki"},
+	}
+	if diff := cmp.Diff(want, multi.EventSources()); diff != "" {
+		t.Errorf("Mismatch for multiple sources (-want +got):\n%s", diff)
+	}
+}
+
+func TestCheckSources(t *testing.T) {
+	tests := []struct {
+		name    string
+		sources []Source
+		wantErr bool
+	}{
+		{name: "valid", sources: []Source{{Name: "master1"}, {Name: "master2"}}},
+		{name: "missing name", sources: []Source{{Name: "master1"}, {}}, wantErr: true},
+		{name: "duplicate name", sources: []Source{{Name: "master1"}, {Name: "master1"}}, wantErr: true},
+		{name: "missing token", sources: []Source{{Name: "master1", SaltAPI: SaltAPI{URL: "https://master1:8000"}}}, wantErr: true},
+	}
+
+	for _, test := range tests {
+		cfg := Config{Sources: test.sources, Reconnect: listener.DefaultBackoff}
+		cfg.EventQueue.OverflowPolicy = listener.OverflowBlock
+		cfg.Metrics.SaltJobDurationSeconds.Type = metrics.GaugeType
+		if err := checkRequirements(cfg); (err != nil) != test.wantErr {
+			t.Errorf("%s: checkRequirements() = %v, wantErr %v", test.name, err, test.wantErr)
+		}
+	}
+}
+
+func TestCheckJobTimeout(t *testing.T) {
+	tests := []struct {
+		name             string
+		missingResponses bool
+		latency          bool
+		timeout          time.Duration
+		wantErr          bool
+	}{
+		{name: "disabled", timeout: 0},
+		{name: "valid", missingResponses: true, latency: true, timeout: time.Minute},
+		{name: "missing responses without timeout", missingResponses: true, timeout: 0, wantErr: true},
+		{name: "latency without timeout", latency: true, timeout: 0, wantErr: true},
+		{name: "negative timeout", latency: true, timeout: -time.Minute, wantErr: true},
+	}
+
+	for _, test := range tests {
+		cfg := Config{Reconnect: listener.DefaultBackoff}
+		cfg.EventQueue.OverflowPolicy = listener.OverflowBlock
+		cfg.Metrics.SaltJobDurationSeconds.Type = metrics.GaugeType
+		cfg.Metrics.SaltJobMissingResponsesTotal.Enabled = test.missingResponses
+		cfg.Metrics.SaltJobMissingResponsesTotal.Timeout = test.timeout
+		cfg.Metrics.SaltJobResponseLatencySeconds.Enabled = test.latency
+		if err := checkRequirements(cfg); (err != nil) != test.wantErr {
+			t.Errorf("%s: checkRequirements() = %v, wantErr %v", test.name, err, test.wantErr)
+		}
+	}
+}
+
 func TestConfigFileOnly(t *testing.T) {
 	name := os.Args[0]
 	backupArgs := os.Args
@@ -262,6 +547,24 @@ func TestConfigFileOnly(t *testing.T) {
 		ListenPort:    2113,
 		IPCFile:       "/dev/null",
 		PKIDir:        "/tmp/pki",
+		SaltAPI: SaltAPI{
+			URL:   "https://salt-master:8000",
+			Token: "sometoken",
+		},
+		Sources: []Source{
+			{
+				Name:    "master1",
+				IPCFile: "tcp://master1:4512",
+				PKIDir:  "/tmp/pki/master1",
+			},
+			{
+				Name: "master2",
+				SaltAPI: SaltAPI{
+					URL:   "https://master2:8000",
+					Token: "othertoken",
+				},
+			},
+		},
 		TLS: struct {
 			Enabled     bool
 			Key         string
@@ -271,6 +574,26 @@ func TestConfigFileOnly(t *testing.T) {
 			Key:         "/path/to/key",
 			Certificate: "/path/to/certificate",
 		},
+		WebConfigFile: "/path/to/web-config.yml",
+		Readiness: struct {
+			MaxEventAge time.Duration `mapstructure:"max-event-age"`
+		}{
+			MaxEventAge: 10 * time.Minute,
+		},
+		Reconnect: listener.Backoff{
+			InitialInterval: 2 * time.Second,
+			MaxInterval:     5 * time.Minute,
+			Multiplier:      1.5,
+			Jitter:          0.1,
+			MaxRetries:      10,
+		},
+		EventQueue: struct {
+			Size           int
+			OverflowPolicy listener.OverflowPolicy `mapstructure:"overflow-policy"`
+		}{
+			Size:           5000,
+			OverflowPolicy: listener.OverflowDropOldest,
+		},
 		Metrics: metrics.Config{
 			HealthMinions: true,
 			Global: struct {
@@ -278,6 +601,8 @@ func TestConfigFileOnly(t *testing.T) {
 					IgnoreTest bool `mapstructure:"ignore-test"`
 					IgnoreMock bool `mapstructure:"ignore-mock"`
 				}
+				MinionSeriesTTL    time.Duration `mapstructure:"minion-series-ttl"`
+				MaxSeriesPerMetric int           `mapstructure:"max-series-per-metric"`
 			}{
 				Filters: struct {
 					IgnoreTest bool `mapstructure:"ignore-test"`
@@ -286,7 +611,10 @@ func TestConfigFileOnly(t *testing.T) {
 					IgnoreTest: true,
 					IgnoreMock: false,
 				},
+				MinionSeriesTTL:    24 * time.Hour,
+				MaxSeriesPerMetric: 10000,
 			},
+			Handlers: []string{"builtin"},
 			SaltNewJobTotal: struct{ Enabled bool }{
 				Enabled: true,
 			},
@@ -333,9 +661,119 @@ func TestConfigFileOnly(t *testing.T) {
 			SaltJobDurationSeconds: struct {
 				Enabled        bool
 				AddMinionLabel bool `mapstructure:"add-minion-label"`
+				Type           string
+				Buckets        []float64
 			}{
 				Enabled:        true,
 				AddMinionLabel: false,
+				Type:           "histogram",
+				Buckets:        []float64{10, 60, 600},
+			},
+			SaltStateDurationSeconds: struct {
+				Enabled        bool
+				AddMinionLabel bool `mapstructure:"add-minion-label"`
+				Buckets        []float64
+			}{
+				Enabled:        false,
+				AddMinionLabel: false,
+				Buckets:        []float64{0.1, 0.5, 1, 5, 10, 30, 60, 120, 300, 600},
+			},
+			SaltStateSlowestDurationSeconds: struct {
+				Enabled        bool
+				AddMinionLabel bool `mapstructure:"add-minion-label"`
+				Top            int
+			}{
+				Enabled:        false,
+				AddMinionLabel: false,
+				Top:            5,
+			},
+			SaltOrchestrateStepResult: struct{ Enabled bool }{
+				Enabled: true,
+			},
+			SaltOrchestrateStepDurationSeconds: struct{ Enabled bool }{
+				Enabled: true,
+			},
+			SaltOrchestrateDurationSeconds: struct{ Enabled bool }{
+				Enabled: true,
+			},
+			SaltWheelFunctionTotal: struct{ Enabled bool }{
+				Enabled: true,
+			},
+			SaltReactorExecutionsTotal: struct{ Enabled bool }{
+				Enabled: true,
+			},
+			SaltKeyEventsTotal: struct{ Enabled bool }{
+				Enabled: true,
+			},
+			SaltKeys: struct{ Enabled bool }{
+				Enabled: false,
+			},
+			SaltMinionAuthTotal: struct {
+				Enabled        bool
+				AddMinionLabel bool `mapstructure:"add-minion-label"`
+			}{
+				Enabled: true,
+			},
+			SaltMinionStartTotal: struct {
+				Enabled        bool
+				AddMinionLabel bool `mapstructure:"add-minion-label"`
+			}{
+				Enabled: true,
+			},
+			SaltMinionLastStart: struct{ Enabled bool }{
+				Enabled: false,
+			},
+			SaltMinionConnected: struct{ Enabled bool }{
+				Enabled: true,
+			},
+			SaltMinionConnectTotal: struct {
+				Enabled        bool
+				AddMinionLabel bool `mapstructure:"add-minion-label"`
+			}{
+				Enabled:        true,
+				AddMinionLabel: false,
+			},
+			SaltMinionDisconnectTotal: struct {
+				Enabled        bool
+				AddMinionLabel bool `mapstructure:"add-minion-label"`
+			}{
+				Enabled:        true,
+				AddMinionLabel: false,
+			},
+			Beacons: []metrics.BeaconMetric{
+				{
+					Beacon: "diskusage",
+					Name:   "salt_beacon_diskusage_percent",
+					Help:   "Disk usage in percent",
+					Value:  "diskusage",
+					Labels: map[string]string{"mount": "mount"},
+				},
+				{
+					Beacon:   "load",
+					Name:     "salt_beacon_load",
+					Value:    "*",
+					KeyLabel: "period",
+				},
+			},
+			CustomEvents: []metrics.CustomEvent{
+				{Tag: "myorg/deploy/*", Value: "duration"},
+				{Tag: "myorg/backup/finished"},
+			},
+			SaltJobMissingResponsesTotal: struct {
+				Enabled bool
+				Timeout time.Duration
+			}{
+				Enabled: true,
+				Timeout: 5 * time.Minute,
+			},
+			SaltJobResponseLatencySeconds: struct {
+				Enabled        bool
+				AddMinionLabel bool `mapstructure:"add-minion-label"`
+				Buckets        []float64
+			}{
+				Enabled:        false,
+				AddMinionLabel: false,
+				Buckets:        []float64{0.5, 1, 2.5, 5, 10, 30, 60, 120, 300, 600, 1800},
 			},
 		},
 	}
@@ -381,6 +819,24 @@ func TestConfigFileWithFlags(t *testing.T) {
 		ListenPort:    8080,
 		IPCFile:       "/somewhere",
 		PKIDir:        "/tmp/pki",
+		SaltAPI: SaltAPI{
+			URL:   "https://salt-master:8000",
+			Token: "sometoken",
+		},
+		Sources: []Source{
+			{
+				Name:    "master1",
+				IPCFile: "tcp://master1:4512",
+				PKIDir:  "/tmp/pki/master1",
+			},
+			{
+				Name: "master2",
+				SaltAPI: SaltAPI{
+					URL:   "https://master2:8000",
+					Token: "othertoken",
+				},
+			},
+		},
 		TLS: struct {
 			Enabled     bool
 			Key         string
@@ -390,6 +846,26 @@ func TestConfigFileWithFlags(t *testing.T) {
 			Key:         "/path/to/key",
 			Certificate: "/path/to/certificate",
 		},
+		WebConfigFile: "/path/to/web-config.yml",
+		Readiness: struct {
+			MaxEventAge time.Duration `mapstructure:"max-event-age"`
+		}{
+			MaxEventAge: 10 * time.Minute,
+		},
+		Reconnect: listener.Backoff{
+			InitialInterval: 2 * time.Second,
+			MaxInterval:     5 * time.Minute,
+			Multiplier:      1.5,
+			Jitter:          0.1,
+			MaxRetries:      10,
+		},
+		EventQueue: struct {
+			Size           int
+			OverflowPolicy listener.OverflowPolicy `mapstructure:"overflow-policy"`
+		}{
+			Size:           5000,
+			OverflowPolicy: listener.OverflowDropOldest,
+		},
 		Metrics: metrics.Config{
 			HealthMinions: false,
 			Global: struct {
@@ -397,6 +873,8 @@ func TestConfigFileWithFlags(t *testing.T) {
 					IgnoreTest bool `mapstructure:"ignore-test"`
 					IgnoreMock bool `mapstructure:"ignore-mock"`
 				}
+				MinionSeriesTTL    time.Duration `mapstructure:"minion-series-ttl"`
+				MaxSeriesPerMetric int           `mapstructure:"max-series-per-metric"`
 			}{
 				Filters: struct {
 					IgnoreTest bool `mapstructure:"ignore-test"`
@@ -405,7 +883,10 @@ func TestConfigFileWithFlags(t *testing.T) {
 					IgnoreTest: true,
 					IgnoreMock: true,
 				},
+				MinionSeriesTTL:    24 * time.Hour,
+				MaxSeriesPerMetric: 10000,
 			},
+			Handlers: []string{"builtin"},
 			SaltNewJobTotal: struct{ Enabled bool }{
 				Enabled: true,
 			},
@@ -452,10 +933,120 @@ func TestConfigFileWithFlags(t *testing.T) {
 			SaltJobDurationSeconds: struct {
 				Enabled        bool
 				AddMinionLabel bool `mapstructure:"add-minion-label"`
+				Type           string
+				Buckets        []float64
+			}{
+				Enabled:        true,
+				AddMinionLabel: false,
+				Type:           "histogram",
+				Buckets:        []float64{10, 60, 600},
+			},
+			SaltStateDurationSeconds: struct {
+				Enabled        bool
+				AddMinionLabel bool `mapstructure:"add-minion-label"`
+				Buckets        []float64
+			}{
+				Enabled:        false,
+				AddMinionLabel: false,
+				Buckets:        []float64{0.1, 0.5, 1, 5, 10, 30, 60, 120, 300, 600},
+			},
+			SaltStateSlowestDurationSeconds: struct {
+				Enabled        bool
+				AddMinionLabel bool `mapstructure:"add-minion-label"`
+				Top            int
+			}{
+				Enabled:        false,
+				AddMinionLabel: false,
+				Top:            5,
+			},
+			SaltOrchestrateStepResult: struct{ Enabled bool }{
+				Enabled: true,
+			},
+			SaltOrchestrateStepDurationSeconds: struct{ Enabled bool }{
+				Enabled: true,
+			},
+			SaltOrchestrateDurationSeconds: struct{ Enabled bool }{
+				Enabled: true,
+			},
+			SaltWheelFunctionTotal: struct{ Enabled bool }{
+				Enabled: true,
+			},
+			SaltReactorExecutionsTotal: struct{ Enabled bool }{
+				Enabled: true,
+			},
+			SaltKeyEventsTotal: struct{ Enabled bool }{
+				Enabled: true,
+			},
+			SaltKeys: struct{ Enabled bool }{
+				Enabled: false,
+			},
+			SaltMinionAuthTotal: struct {
+				Enabled        bool
+				AddMinionLabel bool `mapstructure:"add-minion-label"`
+			}{
+				Enabled: true,
+			},
+			SaltMinionStartTotal: struct {
+				Enabled        bool
+				AddMinionLabel bool `mapstructure:"add-minion-label"`
+			}{
+				Enabled: true,
+			},
+			SaltMinionLastStart: struct{ Enabled bool }{
+				Enabled: false,
+			},
+			SaltMinionConnected: struct{ Enabled bool }{
+				Enabled: true,
+			},
+			SaltMinionConnectTotal: struct {
+				Enabled        bool
+				AddMinionLabel bool `mapstructure:"add-minion-label"`
+			}{
+				Enabled:        true,
+				AddMinionLabel: false,
+			},
+			SaltMinionDisconnectTotal: struct {
+				Enabled        bool
+				AddMinionLabel bool `mapstructure:"add-minion-label"`
 			}{
 				Enabled:        true,
 				AddMinionLabel: false,
 			},
+			Beacons: []metrics.BeaconMetric{
+				{
+					Beacon: "diskusage",
+					Name:   "salt_beacon_diskusage_percent",
+					Help:   "Disk usage in percent",
+					Value:  "diskusage",
+					Labels: map[string]string{"mount": "mount"},
+				},
+				{
+					Beacon:   "load",
+					Name:     "salt_beacon_load",
+					Value:    "*",
+					KeyLabel: "period",
+				},
+			},
+			CustomEvents: []metrics.CustomEvent{
+				{Tag: "myorg/deploy/*", Value: "duration"},
+				{Tag: "myorg/backup/finished"},
+			},
+			SaltJobMissingResponsesTotal: struct {
+				Enabled bool
+				Timeout time.Duration
+			}{
+				Enabled: true,
+				Timeout: 5 * time.Minute,
+			},
+			SaltJobResponseLatencySeconds: struct {
+				Enabled        bool
+				AddMinionLabel bool `mapstructure:"add-minion-label"`
+				Buckets        []float64
+			}{
+				Enabled:        false,
+				AddMinionLabel: false,
+				Buckets:        []float64{0.5, 1, 2.5, 5, 10, 30, 60, 120, 300, 600, 1800},
+			},
 		},
 	}
 
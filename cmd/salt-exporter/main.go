@@ -9,11 +9,16 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/kpetremann/salt-exporter/internal/health"
 	"github.com/kpetremann/salt-exporter/internal/logging"
 	"github.com/kpetremann/salt-exporter/internal/metrics"
+	"github.com/kpetremann/salt-exporter/internal/web"
 	"github.com/kpetremann/salt-exporter/pkg/event"
+	"github.com/kpetremann/salt-exporter/pkg/handler"
 	"github.com/kpetremann/salt-exporter/pkg/listener"
 	"github.com/kpetremann/salt-exporter/pkg/parser"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/rs/zerolog/log"
 )
@@ -52,40 +57,68 @@ func start(config Config) {
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
-	log.Info().Msg("listening for events...")
-	eventChan := make(chan event.SaltEvent)
-	watchChan := make(chan event.WatchEvent)
+	var webConfig web.Config
+	if config.WebConfigFile != "" {
+		var err error
+		if webConfig, err = web.LoadConfig(config.WebConfigFile); err != nil {
+			log.Fatal().Err(err).Msg("failed to load the web config") //nolint:gocritic // force exit
+		}
+		if config.TLS.Enabled && webConfig.TLSServerConfig != nil {
+			log.Fatal().Msg("TLS can't be enabled both in the configuration and in the web config file")
+		}
+	}
 
-	// listen and expose metric
-	parser := parser.NewEventParser(false)
-	eventListener := listener.NewEventListener(ctx, parser, eventChan)
-	eventListener.SetIPCFilepath(config.IPCFile)
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
 
-	if config.Metrics.HealthMinions {
-		pkiWatcher, err := listener.NewPKIWatcher(ctx, config.PKIDir, watchChan)
-		if err != nil {
-			log.Fatal().Msgf("unable to watch PKI for minions change: %v", err) //nolint:gocritic // force exit
-		}
+	if err := metrics.RegisterBuiltinHandler(config.Metrics); err != nil {
+		log.Fatal().Err(err).Msg("failed to register the built-in metrics") //nolint:gocritic // force exit
+	}
+	status := health.NewStatus(config.Readiness.MaxEventAge)
 
-		go pkiWatcher.StartWatching()
+	// with multiple masters, the minions return their jobs to all of them
+	sources := config.EventSources()
+	var deduplicator *metrics.JobDeduplicator
+	if len(sources) > 1 {
+		deduplicator = metrics.NewJobDeduplicator(config.Metrics.SaltJobMissingResponsesTotal.Timeout)
+	}
+
+	log.Info().Msg("listening for events...")
+	for _, source := range sources {
+		if err := startSource(ctx, config, source, registry, status, deduplicator); err != nil {
+			log.Fatal().Err(err).Str("source", source.Name).Msg("failed to start the event source") //nolint:gocritic // force exit
+		}
 	}
-	go eventListener.ListenEvents()
-	go metrics.ExposeMetrics(ctx, eventChan, watchChan, config.Metrics)
 
 	// start http server
 	log.Info().Msg("exposing metrics on " + listenSocket + "/metrics")
 
 	mux := http.NewServeMux()
-	mux.Handle("/metrics", promhttp.Handler())
+	mux.Handle("/metrics", webConfig.BasicAuth(
+		promhttp.InstrumentMetricHandler(registry, promhttp.HandlerFor(registry, promhttp.HandlerOpts{})),
+	))
+	mux.HandleFunc("/healthz", status.HealthHandler)
+	mux.HandleFunc("/readyz", status.ReadyHandler)
 	httpServer := http.Server{Addr: listenSocket, Handler: mux, ReadHeaderTimeout: 2 * time.Second}
 
+	if webConfig.TLSServerConfig != nil {
+		tlsConfig, err := webConfig.TLSServerConfig.ServerConfig()
+		if err != nil {
+			log.Fatal().Err(err).Msg("invalid web config TLS settings")
+		}
+		httpServer.TLSConfig = tlsConfig
+	}
+
 	go func() {
 		var err error
 
-		if !config.TLS.Enabled {
-			err = httpServer.ListenAndServe()
-		} else {
+		switch {
+		case webConfig.TLSServerConfig != nil:
+			err = httpServer.ListenAndServeTLS(webConfig.TLSServerConfig.CertFile, webConfig.TLSServerConfig.KeyFile)
+		case config.TLS.Enabled:
 			err = httpServer.ListenAndServeTLS(config.TLS.Certificate, config.TLS.Key)
+		default:
+			err = httpServer.ListenAndServe()
 		}
 
 		if err != nil {
@@ -101,6 +134,85 @@ func start(config Config) {
 	}
 }
 
+// startSource listens to a salt-master and exposes its metrics.
+//
+// The metrics of the named sources have a master label.
+func startSource(
+	ctx context.Context,
+	config Config,
+	source Source,
+	registry *prometheus.Registry,
+	status *health.Status,
+	deduplicator *metrics.JobDeduplicator,
+) error {
+	var registerer prometheus.Registerer = registry
+	if source.Name != "" {
+		registerer = prometheus.WrapRegistererWith(prometheus.Labels{"master": source.Name}, registry)
+	}
+
+	exporterMetrics, err := metrics.NewExporterMetrics(registerer)
+	if err != nil {
+		return fmt.Errorf("failed to create the exporter metrics: %w", err)
+	}
+
+	handlers, err := handler.New(config.Metrics.Handlers, registerer)
+	if err != nil {
+		return fmt.Errorf("failed to create the event handlers: %w", err)
+	}
+
+	eventChan := make(chan event.SaltEvent, config.EventQueue.Size)
+	watchChan := make(chan event.WatchEvent)
+	if err := metrics.RegisterEventQueue(registerer, eventChan); err != nil {
+		return fmt.Errorf("failed to create the event queue metrics: %w", err)
+	}
+
+	// listen and expose metric
+	parser := parser.NewEventParser(false)
+	parser.CustomTags = metrics.CustomTags(config.Metrics.CustomEvents)
+	sourceStatus := status.AddSource(source.Name)
+	observer := listener.MultiObserver(exporterMetrics, sourceStatus)
+
+	// the process exits if a master can't be reached anymore, after the maximum number of retries
+	fatal := func(err error) {
+		if err != nil {
+			log.Fatal().Err(err).Str("source", source.Name).Msg("giving up")
+		}
+	}
+
+	if source.SaltAPI.URL != "" {
+		// the PKI directory is only readable on the master
+		log.Info().Str("source", source.Name).Msg("listening for events from salt-api, the minion keys are not watched")
+
+		saltAPIListener := listener.NewSaltAPIListener(ctx, parser, eventChan, source.SaltAPI.URL, source.SaltAPI.Token)
+		saltAPIListener.SetObserver(observer)
+		saltAPIListener.SetBackoff(config.Reconnect)
+		saltAPIListener.SetOverflowPolicy(config.EventQueue.OverflowPolicy)
+		go func() { fatal(saltAPIListener.ListenEvents()) }()
+	} else {
+		eventListener := listener.NewEventListener(ctx, parser, eventChan)
+		eventListener.SetIPCFilepath(source.IPCFile)
+		eventListener.SetObserver(observer)
+		eventListener.SetBackoff(config.Reconnect)
+		eventListener.SetOverflowPolicy(config.EventQueue.OverflowPolicy)
+
+		if config.Metrics.HealthMinions || config.Metrics.SaltKeys.Enabled {
+			pkiWatcher, err := listener.NewPKIWatcher(ctx, source.PKIDir, watchChan)
+			if err != nil {
+				return fmt.Errorf("unable to watch PKI for minions change: %w", err)
+			}
+			pkiWatcher.SetObserver(exporterMetrics)
+			pkiWatcher.SetBackoff(config.Reconnect)
+			sourceStatus.SetPKIWatcher(pkiWatcher)
+
+			go func() { fatal(pkiWatcher.StartWatching()) }()
+		}
+		go func() { fatal(eventListener.ListenEvents()) }()
+	}
+	go metrics.ExposeMetrics(ctx, eventChan, watchChan, handlers, exporterMetrics, deduplicator, config.Metrics)
+
+	return nil
+}
+
 func main() {
 	defer quit()
 	logging.Configure()
@@ -34,7 +34,9 @@ func main() {
 	maxItems := flag.Int("max-events", 1000, "maximum events to keep in memory")
 	bufferSize := flag.Int("buffer-size", 1000, "buffer size in number of events")
 	filter := flag.String("hard-filter", "", "filter when received (filtered out events are discarded forever)")
-	ipcFilepath := flag.String("ipc-file", listener.DefaultIPCFilepath, "file location of the salt-master event bus")
+	ipcFilepath := flag.String("ipc-file", listener.DefaultIPCFilepath, "file location of the salt-master event bus, or tcp://host:port if ipc_mode is tcp")
+	saltAPIURL := flag.String("salt-api-url", "", "salt-api URL, i.e. https://salt-master:8000 (replaces the event bus)")
+	saltAPIToken := flag.String("salt-api-token", "", "salt-api token")
 	versionCmd := flag.Bool("version", false, "print version")
 	debug := flag.Bool("debug", false, "enable debug mode (log to debug.log)")
 	flag.Parse()
@@ -59,9 +61,14 @@ func main() {
 
 	eventChan := make(chan event.SaltEvent, *bufferSize)
 	parser := parser.NewEventParser(true)
-	eventListener := listener.NewEventListener(ctx, parser, eventChan)
-	eventListener.SetIPCFilepath(*ipcFilepath)
-	go eventListener.ListenEvents()
+	if *saltAPIURL != "" {
+		saltAPIListener := listener.NewSaltAPIListener(ctx, parser, eventChan, *saltAPIURL, *saltAPIToken)
+		go saltAPIListener.ListenEvents()
+	} else {
+		eventListener := listener.NewEventListener(ctx, parser, eventChan)
+		eventListener.SetIPCFilepath(*ipcFilepath)
+		go eventListener.ListenEvents()
+	}
 
 	p := tea.NewProgram(tui.NewModel(eventChan, *maxItems, *filter), tea.WithMouseCellMotion())
 	if _, err := p.Run(); err != nil {
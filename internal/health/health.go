@@ -0,0 +1,196 @@
+package health
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/kpetremann/salt-exporter/pkg/event"
+	"github.com/rs/zerolog/log"
+)
+
+// Initializer is implemented by the components which need some time before being operational, i.e. the PKI watcher.
+type Initializer interface {
+	Initialized() bool
+}
+
+// Check is the result of a single readiness check.
+type Check struct {
+	OK      bool   `json:"ok"`
+	Message string `json:"message,omitempty"`
+}
+
+// Report is the JSON body returned by the health endpoints.
+type Report struct {
+	Status string           `json:"status"`
+	Checks map[string]Check `json:"checks,omitempty"`
+}
+
+// Status tracks the state of the exporter to answer the health and readiness probes.
+type Status struct {
+	lock sync.RWMutex
+
+	// maxEventAge is the maximum delay since the last event before being not ready, disabled if 0
+	maxEventAge time.Duration
+
+	startedAt time.Time
+	sources   []*Source
+}
+
+// Source tracks the state of an event source, i.e. a salt-master.
+//
+// It implements listener.Observer to follow the event bus connection.
+type Source struct {
+	lock sync.RWMutex
+
+	name         string
+	busConnected bool
+	lastEvent    time.Time
+	pkiWatcher   Initializer
+}
+
+// NewStatus creates a status tracker.
+//
+// The exporter is not ready if no event has been received since maxEventAge, this check is disabled if 0.
+func NewStatus(maxEventAge time.Duration) *Status {
+	return &Status{
+		maxEventAge: maxEventAge,
+		startedAt:   time.Now(),
+	}
+}
+
+// AddSource adds an event source which must be ready for the exporter to be ready.
+//
+// The name prefixes its checks, it can be empty if there is only one source.
+func (s *Status) AddSource(name string) *Source {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	source := &Source{name: name}
+	s.sources = append(s.sources, source)
+
+	return source
+}
+
+// SetPKIWatcher sets the PKI watcher which must be initialized for the source to be ready.
+func (s *Source) SetPKIWatcher(watcher Initializer) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.pkiWatcher = watcher
+}
+
+// BusConnected implements listener.Observer.
+func (s *Source) BusConnected(connected bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.busConnected = connected
+}
+
+// BusReconnecting implements listener.Observer.
+func (s *Source) BusReconnecting() {}
+
+// EventParsed implements listener.Observer.
+func (s *Source) EventParsed(_ event.SaltEvent) {
+	s.eventReceived()
+}
+
+// ParseFailed implements listener.Observer.
+//
+// It also refreshes the last event time: the bus is alive even if the event is not supported.
+func (s *Source) ParseFailed(_ error) {
+	s.eventReceived()
+}
+
+func (s *Source) eventReceived() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.lastEvent = time.Now()
+}
+
+// checks runs the readiness checks of the source.
+func (s *Source) checks(checks map[string]Check, now time.Time, startedAt time.Time, maxEventAge time.Duration) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	prefix := ""
+	if s.name != "" {
+		prefix = s.name + "/"
+	}
+
+	if s.busConnected {
+		checks[prefix+"event_bus"] = Check{OK: true}
+	} else {
+		checks[prefix+"event_bus"] = Check{OK: false, Message: "not connected to the event bus"}
+	}
+
+	if s.pkiWatcher != nil {
+		if s.pkiWatcher.Initialized() {
+			checks[prefix+"pki_watcher"] = Check{OK: true}
+		} else {
+			checks[prefix+"pki_watcher"] = Check{OK: false, Message: "PKI watcher not initialized"}
+		}
+	}
+
+	if maxEventAge > 0 {
+		// the delay is computed from the start of the exporter until the first event
+		last := s.lastEvent
+		if last.IsZero() {
+			last = startedAt
+		}
+
+		if age := now.Sub(last); age > maxEventAge {
+			checks[prefix+"last_event"] = Check{
+				OK:      false,
+				Message: fmt.Sprintf("no event since %s", age.Truncate(time.Second)),
+			}
+		} else {
+			checks[prefix+"last_event"] = Check{OK: true}
+		}
+	}
+}
+
+// Readiness runs the readiness checks of all the sources.
+func (s *Status) Readiness(now time.Time) (bool, map[string]Check) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	checks := make(map[string]Check)
+	for _, source := range s.sources {
+		source.checks(checks, now, s.startedAt, s.maxEventAge)
+	}
+
+	ready := true
+	for _, check := range checks {
+		ready = ready && check.OK
+	}
+
+	return ready, checks
+}
+
+// HealthHandler answers the liveness probe: the exporter is healthy as long as it can serve HTTP requests.
+func (s *Status) HealthHandler(w http.ResponseWriter, _ *http.Request) {
+	writeReport(w, http.StatusOK, Report{Status: "ok"})
+}
+
+// ReadyHandler answers the readiness probe with the details of each check.
+func (s *Status) ReadyHandler(w http.ResponseWriter, _ *http.Request) {
+	ready, checks := s.Readiness(time.Now())
+
+	if !ready {
+		writeReport(w, http.StatusServiceUnavailable, Report{Status: "not ready", Checks: checks})
+		return
+	}
+
+	writeReport(w, http.StatusOK, Report{Status: "ok", Checks: checks})
+}
+
+func writeReport(w http.ResponseWriter, code int, report Report) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		log.Error().Err(err).Msg("failed to write health report")
+	}
+}
@@ -0,0 +1,191 @@
+package health
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/kpetremann/salt-exporter/pkg/event"
+)
+
+type fakeWatcher bool
+
+func (f fakeWatcher) Initialized() bool {
+	return bool(f)
+}
+
+func TestReadiness(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name        string
+		connected   bool
+		watcher     Initializer
+		maxEventAge time.Duration
+		lastEvent   time.Time
+		wantReady   bool
+		wantChecks  []string
+	}{
+		{
+			name:       "not connected",
+			connected:  false,
+			wantReady:  false,
+			wantChecks: []string{"event_bus"},
+		},
+		{
+			name:       "connected",
+			connected:  true,
+			wantReady:  true,
+			wantChecks: []string{"event_bus"},
+		},
+		{
+			name:       "PKI watcher not initialized",
+			connected:  true,
+			watcher:    fakeWatcher(false),
+			wantReady:  false,
+			wantChecks: []string{"event_bus", "pki_watcher"},
+		},
+		{
+			name:       "PKI watcher initialized",
+			connected:  true,
+			watcher:    fakeWatcher(true),
+			wantReady:  true,
+			wantChecks: []string{"event_bus", "pki_watcher"},
+		},
+		{
+			name:        "recent event",
+			connected:   true,
+			maxEventAge: time.Minute,
+			lastEvent:   now.Add(-30 * time.Second),
+			wantReady:   true,
+			wantChecks:  []string{"event_bus", "last_event"},
+		},
+		{
+			name:        "old event",
+			connected:   true,
+			maxEventAge: time.Minute,
+			lastEvent:   now.Add(-2 * time.Minute),
+			wantReady:   false,
+			wantChecks:  []string{"event_bus", "last_event"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := NewStatus(test.maxEventAge)
+			source := s.AddSource("")
+			source.BusConnected(test.connected)
+			if test.watcher != nil {
+				source.SetPKIWatcher(test.watcher)
+			}
+			source.lastEvent = test.lastEvent
+
+			ready, checks := s.Readiness(now)
+			if ready != test.wantReady {
+				t.Errorf("ready = %v, want %v (%v)", ready, test.wantReady, checks)
+			}
+
+			var names []string
+			for _, name := range []string{"event_bus", "pki_watcher", "last_event"} {
+				if _, ok := checks[name]; ok {
+					names = append(names, name)
+				}
+			}
+			if diff := cmp.Diff(test.wantChecks, names); diff != "" {
+				t.Errorf("checks mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestReadinessWithoutEvent(t *testing.T) {
+	s := NewStatus(time.Minute)
+	source := s.AddSource("")
+	source.BusConnected(true)
+
+	if ready, _ := s.Readiness(s.startedAt.Add(30 * time.Second)); !ready {
+		t.Errorf("expected ready during the grace period after the start")
+	}
+	if ready, _ := s.Readiness(s.startedAt.Add(2 * time.Minute)); ready {
+		t.Errorf("expected not ready without event since the start")
+	}
+
+	source.EventParsed(event.SaltEvent{})
+	if ready, _ := s.Readiness(time.Now()); !ready {
+		t.Errorf("expected ready after an event")
+	}
+
+	source.lastEvent = time.Time{}
+	source.ParseFailed(errors.New("tag not supported"))
+	if ready, _ := s.Readiness(time.Now()); !ready {
+		t.Errorf("expected ready after an unsupported event")
+	}
+}
+
+func TestReadinessMultipleSources(t *testing.T) {
+	s := NewStatus(0)
+	master1 := s.AddSource("master1")
+	master2 := s.AddSource("master2")
+	master1.BusConnected(true)
+
+	ready, checks := s.Readiness(time.Now())
+	if ready {
+		t.Errorf("expected not ready while master2 is not connected")
+	}
+	if !checks["master1/event_bus"].OK || checks["master2/event_bus"].OK {
+		t.Errorf("unexpected checks: %v", checks)
+	}
+
+	master2.BusConnected(true)
+	if ready, _ := s.Readiness(time.Now()); !ready {
+		t.Errorf("expected ready once all the masters are connected")
+	}
+}
+
+func TestHandlers(t *testing.T) {
+	s := NewStatus(0)
+	source := s.AddSource("")
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		want    int
+		status  string
+	}{
+		{name: "healthz", handler: s.HealthHandler, want: http.StatusOK, status: "ok"},
+		{name: "readyz", handler: s.ReadyHandler, want: http.StatusServiceUnavailable, status: "not ready"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			test.handler(rec, httptest.NewRequest(http.MethodGet, "/"+test.name, nil))
+
+			if rec.Code != test.want {
+				t.Errorf("status code = %d, want %d", rec.Code, test.want)
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+				t.Errorf("content type = %s, want application/json", ct)
+			}
+
+			var report Report
+			if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+				t.Fatalf("invalid JSON: %v", err)
+			}
+			if report.Status != test.status {
+				t.Errorf("status = %s, want %s", report.Status, test.status)
+			}
+		})
+	}
+
+	source.BusConnected(true)
+	rec := httptest.NewRecorder()
+	s.ReadyHandler(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("status code = %d, want %d once connected", rec.Code, http.StatusOK)
+	}
+}
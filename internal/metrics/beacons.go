@@ -0,0 +1,183 @@
+package metrics
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// pathWildcard matches any key of the beacon data.
+const pathWildcard = "*"
+
+// BeaconMetric declares a gauge updated from a numeric field of the beacon events.
+type BeaconMetric struct {
+	// Beacon is the name of the beacon, as found in the salt/beacon/<minion>/<beacon> tag
+	Beacon string
+	// Name is the name of the metric
+	Name string
+	Help string
+	// Value is the "." separated path of the numeric field in the beacon data.
+	// One key can be replaced by "*" to match any key.
+	Value string
+	// KeyLabel is the label containing the key matched by "*"
+	KeyLabel string `mapstructure:"key-label"`
+	// Labels maps the label names to the "." separated path of their value in the beacon data
+	Labels map[string]string
+}
+
+type beaconGauge struct {
+	config BeaconMetric
+	// labels are the names of the labels extracted from the beacon data, sorted
+	labels []string
+	gauge  *limitedGaugeVec
+}
+
+// ValidateBeaconMetric checks the beacon metric declaration.
+func ValidateBeaconMetric(m BeaconMetric) error {
+	if m.Beacon == "" || m.Name == "" || m.Value == "" {
+		return fmt.Errorf("beacon metric '%s': beacon, name and value are mandatory", m.Name)
+	}
+
+	wildcards := strings.Count(m.Value, pathWildcard)
+	if wildcards > 1 {
+		return fmt.Errorf("beacon metric '%s': only one wildcard is supported", m.Name)
+	}
+	if wildcards == 1 && m.KeyLabel == "" {
+		return fmt.Errorf("beacon metric '%s': key-label is mandatory with a wildcard", m.Name)
+	}
+
+	return nil
+}
+
+func newBeaconGauge(config BeaconMetric, limits seriesLimits) beaconGauge {
+	labels := make([]string, 0, len(config.Labels))
+	for label := range config.Labels {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+
+	allLabels := []string{"minion"}
+	if config.KeyLabel != "" {
+		allLabels = append(allLabels, config.KeyLabel)
+	}
+	allLabels = append(allLabels, labels...)
+
+	help := config.Help
+	if help == "" {
+		help = fmt.Sprintf("Value of %s from %s beacon", config.Value, config.Beacon)
+	}
+
+	return beaconGauge{
+		config: config,
+		labels: labels,
+		gauge: limits.newGaugeVec(
+			prometheus.GaugeOpts{
+				Name: config.Name,
+				Help: help,
+			},
+			allLabels,
+		),
+	}
+}
+
+// update sets the gauge from the beacon data.
+func (b beaconGauge) update(minion string, data any) {
+	labelValues := make([]string, 0, len(b.labels))
+	for _, label := range b.labels {
+		values := lookupPath(data, strings.Split(b.config.Labels[label], "."))
+		if len(values) != 1 {
+			return
+		}
+		labelValues = append(labelValues, fmt.Sprint(values[0].value))
+	}
+
+	for _, match := range lookupPath(data, strings.Split(b.config.Value, ".")) {
+		value, ok := toFloat64(match.value)
+		if !ok {
+			continue
+		}
+
+		labels := []string{minion}
+		if b.config.KeyLabel != "" {
+			labels = append(labels, match.key)
+		}
+		labels = append(labels, labelValues...)
+
+		b.gauge.WithLabelValues(labels...).Set(value)
+	}
+}
+
+type pathMatch struct {
+	// key is the key matched by the wildcard, if any
+	key   string
+	value any
+}
+
+// lookupPath returns the values found at the given path.
+func lookupPath(data any, path []string) []pathMatch {
+	if len(path) == 0 {
+		return []pathMatch{{value: data}}
+	}
+
+	fields, ok := data.(map[string]any)
+	if !ok {
+		return nil
+	}
+
+	if path[0] != pathWildcard {
+		value, ok := fields[path[0]]
+		if !ok {
+			return nil
+		}
+		return lookupPath(value, path[1:])
+	}
+
+	var matches []pathMatch
+	for key, value := range fields {
+		for _, match := range lookupPath(value, path[1:]) {
+			match.key = key
+			matches = append(matches, match)
+		}
+	}
+	return matches
+}
+
+// toFloat64 converts a beacon value to a metric value.
+//
+// Booleans are converted to 0/1, and numeric strings like "42%" are supported.
+func toFloat64(value any) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int8:
+		return float64(v), true
+	case int16:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint8:
+		return float64(v), true
+	case uint16:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case bool:
+		return boolToFloat64(v), true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(v), "%"), 64)
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,134 @@
+package metrics
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/kpetremann/salt-exporter/pkg/parser"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+func TestLookupPath(t *testing.T) {
+	data := map[string]any{
+		"loadavg": map[string]any{
+			"1-min":  0.35,
+			"5-min":  0.48,
+			"15-min": 0.26,
+		},
+		"nginx": map[string]any{"running": true},
+		"sshd":  map[string]any{"running": false},
+		"mount": "/",
+	}
+
+	tests := []struct {
+		name string
+		path []string
+		want []pathMatch
+	}{
+		{
+			name: "simple field",
+			path: []string{"mount"},
+			want: []pathMatch{{value: "/"}},
+		},
+		{
+			name: "nested field",
+			path: []string{"loadavg", "5-min"},
+			want: []pathMatch{{value: 0.48}},
+		},
+		{
+			name: "wildcard as leaf",
+			path: []string{"loadavg", "*"},
+			want: []pathMatch{{key: "1-min", value: 0.35}, {key: "15-min", value: 0.26}, {key: "5-min", value: 0.48}},
+		},
+		{
+			name: "wildcard in the middle",
+			path: []string{"*", "running"},
+			want: []pathMatch{{key: "nginx", value: true}, {key: "sshd", value: false}},
+		},
+		{
+			name: "missing field",
+			path: []string{"loadavg", "30-min"},
+			want: nil,
+		},
+	}
+
+	for _, test := range tests {
+		got := lookupPath(data, test.path)
+		sort.Slice(got, func(i, j int) bool { return got[i].key < got[j].key })
+		if diff := cmp.Diff(got, test.want, cmp.AllowUnexported(pathMatch{})); diff != "" {
+			t.Errorf("Mismatch for '%s' test:\n%s", test.name, diff)
+		}
+	}
+}
+
+func TestToFloat64(t *testing.T) {
+	tests := []struct {
+		value  any
+		want   float64
+		wantOk bool
+	}{
+		{value: 1.5, want: 1.5, wantOk: true},
+		{value: int8(3), want: 3, wantOk: true},
+		{value: uint16(300), want: 300, wantOk: true},
+		{value: true, want: 1, wantOk: true},
+		{value: "42%", want: 42, wantOk: true},
+		{value: "running", want: 0, wantOk: false},
+		{value: nil, want: 0, wantOk: false},
+	}
+
+	for _, test := range tests {
+		got, ok := toFloat64(test.value)
+		if got != test.want || ok != test.wantOk {
+			t.Errorf("Mismatch for '%v', wants %v (%t) got %v (%t)", test.value, test.want, test.wantOk, got, ok)
+		}
+	}
+}
+
+func TestUpdateBeaconMetrics(t *testing.T) {
+	config := testConfig()
+	config.Beacons = []BeaconMetric{
+		{Beacon: "diskusage", Name: "salt_beacon_diskusage_percent", Value: "diskusage", Labels: map[string]string{"mount": "mount"}},
+		{Beacon: "status", Name: "salt_beacon_loadavg", Value: "loadavg.*", KeyLabel: "period"},
+	}
+
+	r, err := NewRegistry(config, prometheus.NewRegistry())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// the diskusage beacon sends its fields at the top level of the event, the status beacon under "data"
+	diskusage, err := msgpack.Marshal(map[string]any{
+		"diskusage": 50.5, "mount": "/", "id": "node1", "_stamp": "2023-10-09T11:36:02.205686",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	status, err := msgpack.Marshal(map[string]any{
+		"data": map[string]any{"loadavg": map[string]any{"1-min": 0.35}}, "id": "node1", "_stamp": "2023-10-09T11:36:02.205686",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	eventParser := parser.NewEventParser(false)
+	for _, body := range []string{
+		"salt/beacon/node1/diskusage/\n\n" + string(diskusage),
+		"salt/beacon/node1/status/2023-10-09T11:36:02.182345\n\n" + string(status),
+	} {
+		e, err := eventParser.Parse(map[string]any{"body": []byte(body)})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		eventToMetrics(e, r)
+	}
+
+	if got := testutil.ToFloat64(r.beaconGauges[0].gauge.WithLabelValues("node1", "/")); got != 50.5 {
+		t.Errorf("salt_beacon_diskusage_percent = %v, want 50.5", got)
+	}
+	if got := testutil.ToFloat64(r.beaconGauges[1].gauge.WithLabelValues("node1", "1-min")); got != 0.35 {
+		t.Errorf("salt_beacon_loadavg = %v, want 0.35", got)
+	}
+}
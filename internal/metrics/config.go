@@ -1,5 +1,14 @@
 package metrics
 
+import "time"
+
+// Supported types for the salt_job_duration_seconds metric.
+const (
+	GaugeType           = "gauge"
+	HistogramType       = "histogram"
+	NativeHistogramType = "native-histogram"
+)
+
 type Config struct {
 	// HealtMinions enable/disable the health functions/states metrics
 	HealthMinions bool `mapstructure:"health-minions"`
@@ -9,8 +18,17 @@ type Config struct {
 			IgnoreTest bool `mapstructure:"ignore-test"`
 			IgnoreMock bool `mapstructure:"ignore-mock"`
 		}
+		// MinionSeriesTTL is the delay after which the series of a minion without new events are deleted.
+		// Disabled if 0.
+		MinionSeriesTTL time.Duration `mapstructure:"minion-series-ttl"`
+		// MaxSeriesPerMetric is the maximum number of series of each metric, unlimited if 0.
+		// The updates of new series beyond the limit are redirected to a single __other__ series, counted in the limit.
+		MaxSeriesPerMetric int `mapstructure:"max-series-per-metric"`
 	}
 
+	// Handlers are the names of the handlers receiving the events
+	Handlers []string
+
 	/*
 		New job metrics
 	*/
@@ -52,5 +70,143 @@ type Config struct {
 	SaltJobDurationSeconds struct {
 		Enabled        bool
 		AddMinionLabel bool `mapstructure:"add-minion-label"`
+		// Type is the kind of metric exposed: gauge, histogram or native-histogram
+		Type    string
+		Buckets []float64
 	} `mapstructure:"salt_job_duration_seconds"`
+
+	/*
+		State metrics
+	*/
+
+	SaltStateResultTotal struct {
+		Enabled bool
+		Filters struct {
+			// Include contains the state IDs to monitor, all if empty
+			Include []string
+			Exclude []string
+		}
+	} `mapstructure:"salt_state_result_total"`
+
+	SaltStateChanges struct {
+		Enabled bool
+	} `mapstructure:"salt_state_changes"`
+
+	SaltStateChangesTotal struct {
+		Enabled        bool
+		AddMinionLabel bool `mapstructure:"add-minion-label"`
+	} `mapstructure:"salt_state_changes_total"`
+
+	SaltStateDurationSeconds struct {
+		Enabled        bool
+		AddMinionLabel bool `mapstructure:"add-minion-label"`
+		Buckets        []float64
+	} `mapstructure:"salt_state_duration_seconds"`
+
+	SaltStateSlowestDurationSeconds struct {
+		Enabled        bool
+		AddMinionLabel bool `mapstructure:"add-minion-label"`
+		// Top is the number of slowest states exposed per SLS
+		Top int
+	} `mapstructure:"salt_state_slowest_duration_seconds"`
+
+	/*
+		Orchestration metrics
+	*/
+
+	SaltOrchestrateStepResult struct {
+		Enabled bool
+	} `mapstructure:"salt_orchestrate_step_result"`
+
+	SaltOrchestrateStepDurationSeconds struct {
+		Enabled bool
+	} `mapstructure:"salt_orchestrate_step_duration_seconds"`
+
+	SaltOrchestrateDurationSeconds struct {
+		Enabled bool
+	} `mapstructure:"salt_orchestrate_duration_seconds"`
+
+	/*
+		Wheel and reactor metrics
+	*/
+
+	SaltWheelFunctionTotal struct {
+		Enabled bool
+	} `mapstructure:"salt_wheel_function_total"`
+
+	SaltReactorExecutionsTotal struct {
+		Enabled bool
+	} `mapstructure:"salt_reactor_executions_total"`
+
+	/*
+		Key metrics
+	*/
+
+	SaltKeyEventsTotal struct {
+		Enabled bool
+	} `mapstructure:"salt_key_events_total"`
+
+	SaltKeys struct {
+		Enabled bool
+	} `mapstructure:"salt_keys"`
+
+	/*
+		Minion connection metrics
+	*/
+
+	SaltMinionAuthTotal struct {
+		Enabled        bool
+		AddMinionLabel bool `mapstructure:"add-minion-label"`
+	} `mapstructure:"salt_minion_auth_total"`
+
+	SaltMinionStartTotal struct {
+		Enabled        bool
+		AddMinionLabel bool `mapstructure:"add-minion-label"`
+	} `mapstructure:"salt_minion_start_total"`
+
+	SaltMinionLastStart struct {
+		Enabled bool
+	} `mapstructure:"salt_minion_last_start"`
+
+	SaltMinionConnected struct {
+		Enabled bool
+	} `mapstructure:"salt_minion_connected"`
+
+	SaltMinionConnectTotal struct {
+		Enabled        bool
+		AddMinionLabel bool `mapstructure:"add-minion-label"`
+	} `mapstructure:"salt_minion_connect_total"`
+
+	SaltMinionDisconnectTotal struct {
+		Enabled        bool
+		AddMinionLabel bool `mapstructure:"add-minion-label"`
+	} `mapstructure:"salt_minion_disconnect_total"`
+
+	/*
+		Beacon metrics
+	*/
+
+	Beacons []BeaconMetric
+
+	/*
+		Custom events metrics
+	*/
+
+	CustomEvents []CustomEvent `mapstructure:"custom_events"`
+
+	/*
+		Job lifecycle metrics
+	*/
+
+	SaltJobMissingResponsesTotal struct {
+		Enabled bool
+		// Timeout is the delay after which a targeted minion without response is considered missing
+		Timeout time.Duration
+	} `mapstructure:"salt_job_missing_responses_total"`
+
+	SaltJobResponseLatencySeconds struct {
+		Enabled        bool
+		AddMinionLabel bool `mapstructure:"add-minion-label"`
+		Buckets        []float64
+	} `mapstructure:"salt_job_response_latency_seconds"`
 }
@@ -0,0 +1,54 @@
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/kpetremann/salt-exporter/internal/filters"
+)
+
+// CustomEvent declares a pattern of custom event tags, i.e. events sent with event.send.
+type CustomEvent struct {
+	// Tag is the pattern of the tags, the wildcard is only supported as a prefix or suffix
+	Tag string
+	// Value is the optional "." separated path of a numeric field in the event data
+	Value string
+}
+
+// ValidateCustomEvent checks the custom event declaration.
+func ValidateCustomEvent(c CustomEvent) error {
+	if c.Tag == "" {
+		return errors.New("custom event: tag is mandatory")
+	}
+	if strings.HasPrefix(c.Tag, "salt/") {
+		return fmt.Errorf("custom event '%s': salt/ tags are not supported", c.Tag)
+	}
+	if strings.Contains(strings.Trim(c.Tag, pathWildcard), pathWildcard) {
+		return fmt.Errorf("custom event '%s': wildcard is only supported as a prefix or suffix", c.Tag)
+	}
+	if strings.Contains(c.Value, pathWildcard) {
+		return fmt.Errorf("custom event '%s': wildcard is not supported in value", c.Tag)
+	}
+
+	return nil
+}
+
+// CustomTags returns the tag patterns of the custom events.
+func CustomTags(customEvents []CustomEvent) []string {
+	tags := make([]string, 0, len(customEvents))
+	for _, c := range customEvents {
+		tags = append(tags, c.Tag)
+	}
+	return tags
+}
+
+// matchCustomEvent returns the first custom event declaration matching the tag.
+func matchCustomEvent(tag string, customEvents []CustomEvent) (CustomEvent, bool) {
+	for _, c := range customEvents {
+		if filters.Match(tag, []string{c.Tag}) {
+			return c, true
+		}
+	}
+	return CustomEvent{}, false
+}
@@ -0,0 +1,51 @@
+package metrics
+
+import (
+	"testing"
+)
+
+func TestMatchCustomEvent(t *testing.T) {
+	customEvents := []CustomEvent{
+		{Tag: "myorg/deploy/finished", Value: "duration"},
+		{Tag: "myorg/deploy/*"},
+		{Tag: "*/backup"},
+	}
+
+	tests := []struct {
+		tag   string
+		want  string
+		found bool
+	}{
+		{tag: "myorg/deploy/finished", want: "myorg/deploy/finished", found: true},
+		{tag: "myorg/deploy/started", want: "myorg/deploy/*", found: true},
+		{tag: "db1/backup", want: "*/backup", found: true},
+		{tag: "myorg/build/finished", found: false},
+	}
+
+	for _, test := range tests {
+		got, found := matchCustomEvent(test.tag, customEvents)
+		if found != test.found || got.Tag != test.want {
+			t.Errorf("matchCustomEvent(%q) = %q, %v; want %q, %v", test.tag, got.Tag, found, test.want, test.found)
+		}
+	}
+}
+
+func TestValidateCustomEvent(t *testing.T) {
+	tests := []struct {
+		name    string
+		event   CustomEvent
+		wantErr bool
+	}{
+		{name: "valid", event: CustomEvent{Tag: "myorg/deploy/*", Value: "duration"}},
+		{name: "missing tag", event: CustomEvent{Value: "duration"}, wantErr: true},
+		{name: "salt tag", event: CustomEvent{Tag: "salt/job/*"}, wantErr: true},
+		{name: "wildcard in the middle", event: CustomEvent{Tag: "myorg/*/finished"}, wantErr: true},
+		{name: "wildcard in value", event: CustomEvent{Tag: "myorg/*", Value: "*.duration"}, wantErr: true},
+	}
+
+	for _, test := range tests {
+		if err := ValidateCustomEvent(test.event); (err != nil) != test.wantErr {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package metrics
+
+import (
+	"sync"
+	"time"
+
+	"github.com/kpetremann/salt-exporter/pkg/event"
+	"github.com/kpetremann/salt-exporter/pkg/handler"
+)
+
+// dedupWindow is how long a job event is remembered to detect its copies from the other masters.
+const dedupWindow = 5 * time.Minute
+
+// sourceHandlers are the handlers of an event source.
+//
+// The handlers are not safe for concurrent use: the lock serializes the events of the source
+// with the job returns routed from the other sources.
+type sourceHandlers struct {
+	lock     sync.Mutex
+	handlers []handler.Handler
+}
+
+func (s *sourceHandlers) handle(e event.SaltEvent) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, h := range s.handlers {
+		h.Handle(e)
+	}
+}
+
+func (s *sourceHandlers) handleWatch(e event.WatchEvent) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, h := range s.handlers {
+		if watcher, ok := h.(handler.WatchHandler); ok {
+			watcher.HandleWatch(e)
+		}
+	}
+}
+
+func (s *sourceHandlers) expire(now time.Time) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, h := range s.handlers {
+		if e, ok := h.(expirer); ok {
+			e.Expire(now)
+		}
+	}
+}
+
+type jobOwner struct {
+	source *sourceHandlers
+	seenAt time.Time
+}
+
+// JobDeduplicator detects the job events received from several masters.
+//
+// With multiple masters, the minions return their jobs to all the masters they are connected to.
+// Only the first copy of each job event is processed, the others are dropped.
+//
+// The job returns are processed by the handlers of the source which received the new job event,
+// so its job tracker sees all the returns whichever master they are received from first.
+//
+// It is shared by the event sources and safe for concurrent use.
+type JobDeduplicator struct {
+	lock sync.Mutex
+
+	// seen maps the job event tags to the time they were first received
+	seen map[string]time.Time
+	// owners maps the jids to the source which received the new job event
+	owners map[string]jobOwner
+	// ownerTTL is how long the source of a job is remembered, at least the job timeout
+	ownerTTL    time.Duration
+	lastCleanup time.Time
+}
+
+// NewJobDeduplicator creates a deduplicator for the job events.
+//
+// jobTimeout is the delay after which the job tracker forgets a job, the returns received later are not routed.
+func NewJobDeduplicator(jobTimeout time.Duration) *JobDeduplicator {
+	return &JobDeduplicator{
+		seen:     make(map[string]time.Time),
+		owners:   make(map[string]jobOwner),
+		ownerTTL: max(jobTimeout, dedupWindow),
+	}
+}
+
+// route returns the handlers processing the event received by the source, false if the event is a duplicate.
+//
+// The tag identifies a job event: salt/job/<jid>/new or salt/job/<jid>/ret/<minion>.
+// The returns of the jobs whose new event has not been received are processed by the source itself.
+func (d *JobDeduplicator) route(e event.SaltEvent, source *sourceHandlers, now time.Time) (*sourceHandlers, bool) {
+	if e.Module != event.JobModule {
+		return source, true
+	}
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if now.Sub(d.lastCleanup) > dedupWindow {
+		d.cleanup(now)
+	}
+
+	if seenAt, ok := d.seen[e.Tag]; ok && now.Sub(seenAt) <= dedupWindow {
+		return nil, false
+	}
+	d.seen[e.Tag] = now
+
+	if e.Data.Jid == "" {
+		return source, true
+	}
+
+	if e.Type == "new" {
+		d.owners[e.Data.Jid] = jobOwner{source: source, seenAt: now}
+		return source, true
+	}
+
+	if owner, ok := d.owners[e.Data.Jid]; ok && now.Sub(owner.seenAt) <= d.ownerTTL {
+		return owner.source, true
+	}
+
+	return source, true
+}
+
+// cleanup forgets the job events received before the window, and the expired job sources.
+func (d *JobDeduplicator) cleanup(now time.Time) {
+	for tag, seenAt := range d.seen {
+		if now.Sub(seenAt) > dedupWindow {
+			delete(d.seen, tag)
+		}
+	}
+	for jid, owner := range d.owners {
+		if now.Sub(owner.seenAt) > d.ownerTTL {
+			delete(d.owners, jid)
+		}
+	}
+	d.lastCleanup = now
+}
@@ -0,0 +1,159 @@
+package metrics
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kpetremann/salt-exporter/pkg/event"
+	"github.com/kpetremann/salt-exporter/pkg/handler"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestJobDeduplicator(t *testing.T) {
+	d := NewJobDeduplicator(time.Minute)
+	now := time.Now()
+	sourceA := &sourceHandlers{}
+	sourceB := &sourceHandlers{}
+
+	newJob := event.SaltEvent{
+		Tag: "salt/job/20231009134437475000/new", Module: event.JobModule, Type: "new",
+		Data: event.EventData{Jid: "20231009134437475000"},
+	}
+	ret := event.SaltEvent{
+		Tag: "salt/job/20231009134437475000/ret/node1", Module: event.JobModule, Type: "ret",
+		Data: event.EventData{Jid: "20231009134437475000"},
+	}
+	otherRet := event.SaltEvent{
+		Tag: "salt/job/20231009134437475000/ret/node2", Module: event.JobModule, Type: "ret",
+		Data: event.EventData{Jid: "20231009134437475000"},
+	}
+	unknownJobRet := event.SaltEvent{
+		Tag: "salt/job/20231009134437475001/ret/node1", Module: event.JobModule, Type: "ret",
+		Data: event.EventData{Jid: "20231009134437475001"},
+	}
+	beacon := event.SaltEvent{Tag: "salt/beacon/node1/status/", Module: event.BeaconModule, Type: "status"}
+
+	tests := []struct {
+		name   string
+		event  event.SaltEvent
+		source *sourceHandlers
+		at     time.Time
+		want   *sourceHandlers
+		wantOk bool
+	}{
+		{name: "new job", event: newJob, source: sourceA, at: now, want: sourceA, wantOk: true},
+		{name: "return routed to the job source", event: ret, source: sourceB, at: now, want: sourceA, wantOk: true},
+		{name: "copy from another master", event: ret, source: sourceA, at: now.Add(time.Second), wantOk: false},
+		{name: "other minion", event: otherRet, source: sourceA, at: now.Add(time.Second), want: sourceA, wantOk: true},
+		{name: "unknown job", event: unknownJobRet, source: sourceB, at: now, want: sourceB, wantOk: true},
+		{name: "not a job event", event: beacon, source: sourceB, at: now, want: sourceB, wantOk: true},
+		{name: "not a job event again", event: beacon, source: sourceB, at: now.Add(time.Second), want: sourceB, wantOk: true},
+		{name: "after the window", event: ret, source: sourceB, at: now.Add(dedupWindow + time.Minute), want: sourceB, wantOk: true},
+	}
+
+	for _, test := range tests {
+		got, ok := d.route(test.event, test.source, test.at)
+		if got != test.want || ok != test.wantOk {
+			t.Errorf("%s: route() = %p (%t), want %p (%t)", test.name, got, ok, test.want, test.wantOk)
+		}
+	}
+
+	if _, ok := d.seen[otherRet.Tag]; ok {
+		t.Errorf("expired job event not cleaned up")
+	}
+	if _, ok := d.owners[newJob.Data.Jid]; ok {
+		t.Errorf("expired job source not cleaned up")
+	}
+}
+
+func TestExposeMetricsMultipleMasters(t *testing.T) {
+	config := testConfig()
+	config.Global.Filters.IgnoreTest = true
+	config.SaltJobMissingResponsesTotal.Enabled = true
+	config.SaltJobMissingResponsesTotal.Timeout = time.Minute
+	config.SaltJobResponseLatencySeconds.Enabled = true
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	deduplicator := NewJobDeduplicator(config.SaltJobMissingResponsesTotal.Timeout)
+
+	type master struct {
+		registry        *Registry
+		exporterMetrics *ExporterMetrics
+		eventChan       chan event.SaltEvent
+		done            chan struct{}
+	}
+	masters := make([]master, 2)
+	for i := range masters {
+		r, err := NewRegistry(config, prometheus.NewRegistry())
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		exporterMetrics, err := NewExporterMetrics(prometheus.NewRegistry())
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		masters[i] = master{registry: r, exporterMetrics: exporterMetrics, eventChan: make(chan event.SaltEvent), done: make(chan struct{})}
+
+		go func() {
+			ExposeMetrics(ctx, masters[i].eventChan, nil, []handler.Handler{r}, exporterMetrics, deduplicator, config)
+			close(masters[i].done)
+		}()
+	}
+	a, b := masters[0], masters[1]
+
+	// the channels are unbuffered: once an ignored event is received, the previous one has been processed
+	flush := func(m master) {
+		m.eventChan <- event.SaltEvent{IsTest: true}
+	}
+
+	newJob := stateSlsEvent("new")
+	newJob.Data.Timestamp = "2023-10-09T09:20:21.000000"
+	a.eventChan <- newJob
+	flush(a)
+
+	for _, minion := range []string{"node1", "node2"} {
+		ret := stateSlsEvent("ret")
+		ret.Tag += "/" + minion
+		ret.Data.ID = minion
+		ret.Data.Timestamp = "2023-10-09T09:20:22.000000"
+
+		// the master which did not publish the job receives the return first
+		b.eventChan <- ret
+		flush(b)
+		a.eventChan <- ret
+		flush(a)
+	}
+
+	cancel()
+	<-a.done
+	<-b.done
+
+	for _, minion := range []string{"node1", "node2"} {
+		if got := testutil.ToFloat64(a.registry.responseTotal.WithLabelValues(minion, "true")); got != 1 {
+			t.Errorf("salt_responses_total{minion=%q} = %v, want 1", minion, got)
+		}
+	}
+	if got := testutil.CollectAndCount(b.registry.responseTotal); got != 0 {
+		t.Errorf("Returns should be processed by the master which published the job, got %d series", got)
+	}
+	if got := testutil.ToFloat64(a.exporterMetrics.duplicateEventsTotal); got != 2 {
+		t.Errorf("salt_exporter_duplicate_events_total = %v, want 2", got)
+	}
+
+	if got := testutil.CollectAndCount(a.registry.jobResponseLatency); got != 1 {
+		t.Errorf("salt_job_response_latency_seconds should be observed, got %d series", got)
+	}
+	if len(a.registry.jobs.jobs) != 0 {
+		t.Errorf("Job with all responses should not be tracked anymore, got %d jobs", len(a.registry.jobs.jobs))
+	}
+
+	for _, m := range masters {
+		m.registry.ExpireJobs(time.Now().Add(2 * time.Minute))
+		if got := testutil.CollectAndCount(m.registry.jobMissingResponsesTotal); got != 0 {
+			t.Errorf("Unexpected missing responses: %d series", got)
+		}
+	}
+}
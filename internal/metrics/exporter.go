@@ -0,0 +1,219 @@
+package metrics
+
+import (
+	"errors"
+	"time"
+
+	"github.com/kpetremann/salt-exporter/pkg/event"
+	"github.com/kpetremann/salt-exporter/pkg/parser"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ExporterMetrics exposes metrics about the exporter itself.
+//
+// It implements listener.Observer.
+type ExporterMetrics struct {
+	eventsTotal            *prometheus.CounterVec
+	parseErrorsTotal       *prometheus.CounterVec
+	busReconnectsTotal     prometheus.Counter
+	busConnected           prometheus.Gauge
+	eventProcessingSeconds prometheus.Histogram
+	duplicateEventsTotal   prometheus.Counter
+	droppedEventsTotal     prometheus.Counter
+	retries                *prometheus.GaugeVec
+	retryDelaySeconds      *prometheus.GaugeVec
+}
+
+// components retrying to connect.
+const (
+	eventBusComponent = "event_bus"
+	pkiComponent      = "pki"
+)
+
+// NewExporterMetrics creates the exporter metrics and registers them on the registerer.
+func NewExporterMetrics(registerer prometheus.Registerer) (*ExporterMetrics, error) {
+	m := &ExporterMetrics{
+		eventsTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "salt_exporter_events_total",
+				Help: "Total number of events read from the event bus and successfully parsed",
+			},
+			[]string{"module", "type"},
+		),
+		parseErrorsTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "salt_exporter_parse_errors_total",
+				Help: "Total number of messages read from the event bus which could not be parsed",
+			},
+			[]string{"reason"},
+		),
+		busReconnectsTotal: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Name: "salt_exporter_bus_reconnects_total",
+				Help: "Total number of reconnections to the event bus",
+			},
+		),
+		busConnected: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "salt_exporter_bus_connected",
+				Help: "Connection to the event bus, 0=Disconnected, 1=Connected",
+			},
+		),
+		eventProcessingSeconds: prometheus.NewHistogram(
+			prometheus.HistogramOpts{
+				Name:    "salt_exporter_event_processing_seconds",
+				Help:    "Duration between the reception of an event and the end of its processing in seconds",
+				Buckets: []float64{0.0001, 0.0005, 0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5},
+			},
+		),
+		duplicateEventsTotal: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Name: "salt_exporter_duplicate_events_total",
+				Help: "Total number of job events dropped because already received from another master",
+			},
+		),
+		droppedEventsTotal: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Name: "salt_exporter_dropped_events_total",
+				Help: "Total number of events dropped because the event queue was full",
+			},
+		),
+		retries: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "salt_exporter_connection_retries",
+				Help: "Number of consecutive failed connection attempts, 0 once connected",
+			},
+			[]string{"component"},
+		),
+		retryDelaySeconds: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "salt_exporter_connection_retry_delay_seconds",
+				Help: "Delay before the next connection attempt in seconds, 0 once connected",
+			},
+			[]string{"component"},
+		),
+	}
+
+	for _, c := range []prometheus.Collector{
+		m.eventsTotal,
+		m.parseErrorsTotal,
+		m.busReconnectsTotal,
+		m.busConnected,
+		m.eventProcessingSeconds,
+		m.duplicateEventsTotal,
+		m.droppedEventsTotal,
+		m.retries,
+		m.retryDelaySeconds,
+	} {
+		if err := registerer.Register(c); err != nil {
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
+
+// RegisterEventQueue exposes the number of events waiting in the queue and its capacity.
+//
+// The queue is read on each scrape, a queue length close to the capacity means the processing falls behind.
+func RegisterEventQueue(registerer prometheus.Registerer, queue <-chan event.SaltEvent) error {
+	for _, c := range []prometheus.Collector{
+		prometheus.NewGaugeFunc(
+			prometheus.GaugeOpts{
+				Name: "salt_exporter_event_queue_length",
+				Help: "Number of events waiting in the event queue",
+			},
+			func() float64 { return float64(len(queue)) },
+		),
+		prometheus.NewGaugeFunc(
+			prometheus.GaugeOpts{
+				Name: "salt_exporter_event_queue_capacity",
+				Help: "Maximum number of events in the event queue",
+			},
+			func() float64 { return float64(cap(queue)) },
+		),
+	} {
+		if err := registerer.Register(c); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// parseErrorReason returns the reason label of a parser error.
+func parseErrorReason(err error) string {
+	switch {
+	case errors.Is(err, parser.ErrUnsupportedTag):
+		return "unsupported_tag"
+	case errors.Is(err, parser.ErrUnknownModule):
+		return "unknown_module"
+	case errors.Is(err, parser.ErrInvalidTag):
+		return "invalid_tag"
+	case errors.Is(err, parser.ErrDecodingFailure):
+		return "decoding_failure"
+	default:
+		return "unknown"
+	}
+}
+
+// BusConnected implements listener.Observer.
+func (m *ExporterMetrics) BusConnected(connected bool) {
+	m.busConnected.Set(boolToFloat64(connected))
+	if connected {
+		m.setRetries(eventBusComponent, 0, 0)
+	}
+}
+
+// BusRetrying implements listener.RetryObserver.
+func (m *ExporterMetrics) BusRetrying(retry int, delay time.Duration) {
+	m.setRetries(eventBusComponent, retry, delay)
+}
+
+// PKIRetrying implements listener.PKIObserver.
+func (m *ExporterMetrics) PKIRetrying(retry int, delay time.Duration) {
+	m.setRetries(pkiComponent, retry, delay)
+}
+
+// PKIInitialized implements listener.PKIObserver.
+func (m *ExporterMetrics) PKIInitialized() {
+	m.setRetries(pkiComponent, 0, 0)
+}
+
+func (m *ExporterMetrics) setRetries(component string, retry int, delay time.Duration) {
+	m.retries.WithLabelValues(component).Set(float64(retry))
+	m.retryDelaySeconds.WithLabelValues(component).Set(delay.Seconds())
+}
+
+// BusReconnecting implements listener.Observer.
+func (m *ExporterMetrics) BusReconnecting() {
+	m.busReconnectsTotal.Inc()
+}
+
+// EventParsed implements listener.Observer.
+func (m *ExporterMetrics) EventParsed(e event.SaltEvent) {
+	m.eventsTotal.WithLabelValues(e.Module.String(), e.Type).Inc()
+}
+
+// EventsDropped implements listener.DropObserver.
+func (m *ExporterMetrics) EventsDropped(count int) {
+	m.droppedEventsTotal.Add(float64(count))
+}
+
+// ParseFailed implements listener.Observer.
+func (m *ExporterMetrics) ParseFailed(err error) {
+	m.parseErrorsTotal.WithLabelValues(parseErrorReason(err)).Inc()
+}
+
+// EventProcessed observes the processing latency of the event.
+func (m *ExporterMetrics) EventProcessed(e event.SaltEvent, now time.Time) {
+	if e.ReceivedAt.IsZero() {
+		return
+	}
+	m.eventProcessingSeconds.Observe(now.Sub(e.ReceivedAt).Seconds())
+}
+
+// EventDuplicated counts the job events already received from another master.
+func (m *ExporterMetrics) EventDuplicated() {
+	m.duplicateEventsTotal.Inc()
+}
@@ -0,0 +1,118 @@
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kpetremann/salt-exporter/pkg/event"
+	"github.com/kpetremann/salt-exporter/pkg/parser"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestParseErrorReason(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{err: parser.ErrUnsupportedTag, want: "unsupported_tag"},
+		{err: parser.ErrUnknownModule, want: "unknown_module"},
+		{err: fmt.Errorf("%w: salt/job", parser.ErrInvalidTag), want: "invalid_tag"},
+		{err: fmt.Errorf("%w: %w", parser.ErrDecodingFailure, errors.New("EOF")), want: "decoding_failure"},
+		{err: errors.New("something else"), want: "unknown"},
+	}
+
+	for _, test := range tests {
+		if got := parseErrorReason(test.err); got != test.want {
+			t.Errorf("parseErrorReason(%v) = %s, want %s", test.err, got, test.want)
+		}
+	}
+}
+
+func TestExporterMetrics(t *testing.T) {
+	m, err := NewExporterMetrics(prometheus.NewRegistry())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	received := time.Now()
+	e := event.SaltEvent{Module: event.JobModule, Type: "new", ReceivedAt: received}
+
+	m.BusConnected(true)
+	m.EventParsed(e)
+	m.ParseFailed(parser.ErrUnsupportedTag)
+	m.BusReconnecting()
+	m.BusConnected(false)
+	m.EventProcessed(e, received.Add(time.Millisecond))
+	m.EventProcessed(event.SaltEvent{}, received)
+	m.EventDuplicated()
+	m.EventsDropped(2)
+	m.BusRetrying(3, 4*time.Second)
+	m.PKIRetrying(2, time.Second)
+	m.PKIInitialized()
+
+	if got := testutil.ToFloat64(m.eventsTotal.WithLabelValues("job", "new")); got != 1 {
+		t.Errorf("Unexpected events total: %v", got)
+	}
+	if got := testutil.ToFloat64(m.parseErrorsTotal.WithLabelValues("unsupported_tag")); got != 1 {
+		t.Errorf("Unexpected parse errors total: %v", got)
+	}
+	if got := testutil.ToFloat64(m.busReconnectsTotal); got != 1 {
+		t.Errorf("Unexpected reconnects total: %v", got)
+	}
+	if got := testutil.ToFloat64(m.busConnected); got != 0 {
+		t.Errorf("Unexpected bus connection status: %v", got)
+	}
+	if got := testutil.CollectAndCount(m.eventProcessingSeconds); got != 1 {
+		t.Errorf("Unexpected processing latency series: %v", got)
+	}
+	if got := testutil.ToFloat64(m.retries.WithLabelValues("event_bus")); got != 3 {
+		t.Errorf("Unexpected event bus retries: %v", got)
+	}
+	if got := testutil.ToFloat64(m.retryDelaySeconds.WithLabelValues("event_bus")); got != 4 {
+		t.Errorf("Unexpected event bus retry delay: %v", got)
+	}
+	if got := testutil.ToFloat64(m.retries.WithLabelValues("pki")); got != 0 {
+		t.Errorf("Unexpected PKI retries once initialized: %v", got)
+	}
+	m.BusConnected(true)
+	if got := testutil.ToFloat64(m.retries.WithLabelValues("event_bus")); got != 0 {
+		t.Errorf("Unexpected event bus retries once connected: %v", got)
+	}
+	if got := testutil.ToFloat64(m.droppedEventsTotal); got != 2 {
+		t.Errorf("Unexpected dropped events total: %v", got)
+	}
+	if got := testutil.ToFloat64(m.duplicateEventsTotal); got != 1 {
+		t.Errorf("Unexpected duplicate events total: %v", got)
+	}
+}
+
+func TestRegisterEventQueue(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	queue := make(chan event.SaltEvent, 10)
+
+	if err := RegisterEventQueue(registry, queue); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := RegisterEventQueue(registry, queue); err == nil {
+		t.Errorf("Registering the queue metrics twice on the same registerer should fail")
+	}
+
+	queue <- event.SaltEvent{}
+	queue <- event.SaltEvent{}
+
+	expected := `
+# HELP salt_exporter_event_queue_capacity Maximum number of events in the event queue
+# TYPE salt_exporter_event_queue_capacity gauge
+salt_exporter_event_queue_capacity 10
+# HELP salt_exporter_event_queue_length Number of events waiting in the event queue
+# TYPE salt_exporter_event_queue_length gauge
+salt_exporter_event_queue_length 2
+`
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected)); err != nil {
+		t.Errorf("Unexpected queue metrics: %v", err)
+	}
+}
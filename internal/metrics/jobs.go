@@ -0,0 +1,96 @@
+package metrics
+
+import (
+	"time"
+)
+
+type trackedJob struct {
+	function string
+	state    string
+	started  time.Time
+	deadline time.Time
+
+	// pending contains the targeted minions which did not return yet
+	pending map[string]struct{}
+}
+
+type missingResponse struct {
+	function string
+	state    string
+	minion   string
+}
+
+// jobTracker keeps an in-memory table of in-flight jobs, keyed by JID.
+//
+// A job is added when its "new" event is received, and each targeted minion
+// is marked as returned when its "ret" event is received.
+// Once the timeout elapsed, the minions which did not return are reported as missing.
+type jobTracker struct {
+	jobs map[string]*trackedJob
+}
+
+func newJobTracker() jobTracker {
+	return jobTracker{jobs: make(map[string]*trackedJob)}
+}
+
+// add starts tracking a new job.
+//
+// started is the time at which the job was published, it can be zero if unknown.
+func (t *jobTracker) add(jid, function, state string, minions []string, started, deadline time.Time) {
+	if jid == "" || len(minions) == 0 {
+		return
+	}
+
+	pending := make(map[string]struct{}, len(minions))
+	for _, minion := range minions {
+		pending[minion] = struct{}{}
+	}
+
+	t.jobs[jid] = &trackedJob{
+		function: function,
+		state:    state,
+		started:  started,
+		deadline: deadline,
+		pending:  pending,
+	}
+}
+
+// markReturned flags the minion as returned for the job.
+//
+// It returns the job if the minion was expected to return.
+// The job is forgotten as soon as all targeted minions returned.
+func (t *jobTracker) markReturned(jid, minion string) (trackedJob, bool) {
+	job, ok := t.jobs[jid]
+	if !ok {
+		return trackedJob{}, false
+	}
+
+	if _, ok := job.pending[minion]; !ok {
+		return trackedJob{}, false
+	}
+
+	delete(job.pending, minion)
+	if len(job.pending) == 0 {
+		delete(t.jobs, jid)
+	}
+
+	return *job, true
+}
+
+// expire forgets the jobs which reached their deadline and returns the minions which never returned.
+func (t *jobTracker) expire(now time.Time) []missingResponse {
+	var missing []missingResponse
+
+	for jid, job := range t.jobs {
+		if now.Before(job.deadline) {
+			continue
+		}
+
+		for minion := range job.pending {
+			missing = append(missing, missingResponse{function: job.function, state: job.state, minion: minion})
+		}
+		delete(t.jobs, jid)
+	}
+
+	return missing
+}
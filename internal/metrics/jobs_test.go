@@ -0,0 +1,53 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestJobTracker(t *testing.T) {
+	start := time.Date(2023, 10, 9, 11, 0, 0, 0, time.UTC)
+	deadline := start.Add(time.Minute)
+
+	tracker := newJobTracker()
+	tracker.add("1", "state.highstate", "highstate", []string{"node1", "node2"}, start, deadline)
+	tracker.add("2", "test.ping", "", []string{"node1"}, start, deadline)
+	tracker.add("3", "test.ping", "", []string{}, start, deadline)
+
+	job, ok := tracker.markReturned("1", "node1")
+	if !ok || job.function != "state.highstate" || job.state != "highstate" || !job.started.Equal(start) {
+		t.Errorf("Unexpected job returned: %v", job)
+	}
+	if _, ok := tracker.markReturned("1", "node1"); ok {
+		t.Errorf("Duplicated response should be ignored")
+	}
+	if _, ok := tracker.markReturned("2", "node1"); !ok {
+		t.Errorf("Expected response not flagged")
+	}
+	if _, ok := tracker.markReturned("unknown", "node1"); ok {
+		t.Errorf("Response from unknown job should be ignored")
+	}
+
+	if got := tracker.expire(start); len(got) != 0 {
+		t.Errorf("Unexpected missing responses before deadline: %v", got)
+	}
+
+	if _, ok := tracker.jobs["2"]; ok {
+		t.Errorf("Job with all responses should not be tracked anymore")
+	}
+	if _, ok := tracker.jobs["3"]; ok {
+		t.Errorf("Job without target should not be tracked")
+	}
+
+	want := []missingResponse{{function: "state.highstate", state: "highstate", minion: "node2"}}
+	got := tracker.expire(deadline)
+	if diff := cmp.Diff(got, want, cmp.AllowUnexported(missingResponse{})); diff != "" {
+		t.Errorf("Mismatch:\n%s", diff)
+	}
+
+	if len(tracker.jobs) != 0 {
+		t.Errorf("Expired jobs should not be tracked anymore")
+	}
+}
@@ -0,0 +1,190 @@
+package metrics
+
+import (
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// otherLabelValue replaces the label values of the series exceeding the limit.
+const otherLabelValue = "__other__"
+
+// labelValuesSeparator joins the label values of a series, it can't be part of a valid UTF-8 label value.
+const labelValuesSeparator = "\xff"
+
+// seriesLimits creates metrics having a maximum number of series.
+//
+// Once the limit is reached, the updates of new series are redirected to a single series
+// having all its labels set to __other__. This series is counted in the limit.
+type seriesLimits struct {
+	// max is the maximum number of series per metric, unlimited if 0
+	max     int
+	dropped *prometheus.CounterVec
+}
+
+func newSeriesLimits(maxSeries int) seriesLimits {
+	return seriesLimits{
+		max: maxSeries,
+		dropped: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "salt_exporter_dropped_updates_total",
+				Help: "Total number of updates redirected to the " + otherLabelValue + " series because of the series limit",
+			},
+			[]string{"metric"},
+		),
+	}
+}
+
+// seriesLimiter tracks the series of a metric.
+type seriesLimiter struct {
+	max     int
+	labels  []string
+	series  map[string]struct{}
+	dropped prometheus.Counter
+}
+
+func (l seriesLimits) newLimiter(name string, labels []string) *seriesLimiter {
+	return &seriesLimiter{
+		max:     l.max,
+		labels:  labels,
+		series:  make(map[string]struct{}),
+		dropped: l.dropped.WithLabelValues(name),
+	}
+}
+
+// limit returns the label values to use for the series.
+func (l *seriesLimiter) limit(lvs []string) []string {
+	if l.max <= 0 {
+		return lvs
+	}
+
+	key := strings.Join(lvs, labelValuesSeparator)
+	if _, ok := l.series[key]; ok {
+		return lvs
+	}
+	// the last slot is kept for the __other__ series
+	if len(l.series) < l.max-1 {
+		l.series[key] = struct{}{}
+		return lvs
+	}
+
+	l.dropped.Inc()
+
+	other := make([]string, len(lvs))
+	for i := range other {
+		other[i] = otherLabelValue
+	}
+	return other
+}
+
+// forget frees the slot of a deleted series.
+func (l *seriesLimiter) forget(lvs []string) {
+	delete(l.series, strings.Join(lvs, labelValuesSeparator))
+}
+
+// forgetPartialMatch frees the slots of the series matching the labels.
+func (l *seriesLimiter) forgetPartialMatch(labels prometheus.Labels) {
+	for key := range l.series {
+		lvs := strings.Split(key, labelValuesSeparator)
+		if l.matches(lvs, labels) {
+			delete(l.series, key)
+		}
+	}
+}
+
+func (l *seriesLimiter) matches(lvs []string, labels prometheus.Labels) bool {
+	for name, value := range labels {
+		i := indexOf(l.labels, name)
+		if i < 0 || i >= len(lvs) || lvs[i] != value {
+			return false
+		}
+	}
+	return true
+}
+
+func indexOf(labels []string, name string) int {
+	for i, label := range labels {
+		if label == name {
+			return i
+		}
+	}
+	return -1
+}
+
+type limitedCounterVec struct {
+	*prometheus.CounterVec
+	limiter *seriesLimiter
+}
+
+func (l seriesLimits) newCounterVec(opts prometheus.CounterOpts, labels []string) *limitedCounterVec {
+	return &limitedCounterVec{
+		CounterVec: prometheus.NewCounterVec(opts, labels),
+		limiter:    l.newLimiter(opts.Name, labels),
+	}
+}
+
+func (v *limitedCounterVec) WithLabelValues(lvs ...string) prometheus.Counter {
+	return v.CounterVec.WithLabelValues(v.limiter.limit(lvs)...)
+}
+
+func (v *limitedCounterVec) DeleteLabelValues(lvs ...string) bool {
+	v.limiter.forget(lvs)
+	return v.CounterVec.DeleteLabelValues(lvs...)
+}
+
+func (v *limitedCounterVec) DeletePartialMatch(labels prometheus.Labels) int {
+	v.limiter.forgetPartialMatch(labels)
+	return v.CounterVec.DeletePartialMatch(labels)
+}
+
+type limitedGaugeVec struct {
+	*prometheus.GaugeVec
+	limiter *seriesLimiter
+}
+
+func (l seriesLimits) newGaugeVec(opts prometheus.GaugeOpts, labels []string) *limitedGaugeVec {
+	return &limitedGaugeVec{
+		GaugeVec: prometheus.NewGaugeVec(opts, labels),
+		limiter:  l.newLimiter(opts.Name, labels),
+	}
+}
+
+func (v *limitedGaugeVec) WithLabelValues(lvs ...string) prometheus.Gauge {
+	return v.GaugeVec.WithLabelValues(v.limiter.limit(lvs)...)
+}
+
+func (v *limitedGaugeVec) DeleteLabelValues(lvs ...string) bool {
+	v.limiter.forget(lvs)
+	return v.GaugeVec.DeleteLabelValues(lvs...)
+}
+
+func (v *limitedGaugeVec) DeletePartialMatch(labels prometheus.Labels) int {
+	v.limiter.forgetPartialMatch(labels)
+	return v.GaugeVec.DeletePartialMatch(labels)
+}
+
+type limitedHistogramVec struct {
+	*prometheus.HistogramVec
+	limiter *seriesLimiter
+}
+
+func (l seriesLimits) newHistogramVec(opts prometheus.HistogramOpts, labels []string) *limitedHistogramVec {
+	return &limitedHistogramVec{
+		HistogramVec: prometheus.NewHistogramVec(opts, labels),
+		limiter:      l.newLimiter(opts.Name, labels),
+	}
+}
+
+func (v *limitedHistogramVec) WithLabelValues(lvs ...string) prometheus.Observer {
+	return v.HistogramVec.WithLabelValues(v.limiter.limit(lvs)...)
+}
+
+func (v *limitedHistogramVec) DeleteLabelValues(lvs ...string) bool {
+	v.limiter.forget(lvs)
+	return v.HistogramVec.DeleteLabelValues(lvs...)
+}
+
+func (v *limitedHistogramVec) DeletePartialMatch(labels prometheus.Labels) int {
+	v.limiter.forgetPartialMatch(labels)
+	return v.HistogramVec.DeletePartialMatch(labels)
+}
@@ -0,0 +1,61 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestLimitedCounterVec(t *testing.T) {
+	limits := newSeriesLimits(3)
+	vec := limits.newCounterVec(prometheus.CounterOpts{Name: "test_total"}, []string{"minion", "success"})
+
+	vec.WithLabelValues("node1", "true").Inc()
+	vec.WithLabelValues("node2", "true").Inc()
+	vec.WithLabelValues("node3", "true").Inc()
+	vec.WithLabelValues("node4", "false").Inc()
+	vec.WithLabelValues("node1", "true").Inc()
+
+	if got := testutil.CollectAndCount(vec); got != 3 {
+		t.Errorf("Expected 2 series and the %s one within the limit, got %d series", otherLabelValue, got)
+	}
+	if got := testutil.ToFloat64(vec.WithLabelValues("node1", "true")); got != 2 {
+		t.Errorf("Existing series should be updated, got %v", got)
+	}
+	if got := testutil.ToFloat64(vec.CounterVec.WithLabelValues(otherLabelValue, otherLabelValue)); got != 2 {
+		t.Errorf("Series beyond the limit should be redirected to %s, got %v", otherLabelValue, got)
+	}
+	if got := testutil.ToFloat64(limits.dropped.WithLabelValues("test_total")); got != 2 {
+		t.Errorf("Unexpected dropped updates: %v", got)
+	}
+
+	// deleting series frees slots
+	vec.DeletePartialMatch(prometheus.Labels{"minion": "node2"})
+	vec.WithLabelValues("node5", "true").Inc()
+	if got := testutil.ToFloat64(vec.WithLabelValues("node5", "true")); got != 1 {
+		t.Errorf("New series should be created once a slot is freed, got %v", got)
+	}
+
+	vec.DeleteLabelValues("node1", "true")
+	vec.WithLabelValues("node6", "true").Inc()
+	if got := testutil.ToFloat64(vec.WithLabelValues("node6", "true")); got != 1 {
+		t.Errorf("New series should be created once a slot is freed, got %v", got)
+	}
+}
+
+func TestUnlimitedGaugeVec(t *testing.T) {
+	limits := newSeriesLimits(0)
+	vec := limits.newGaugeVec(prometheus.GaugeOpts{Name: "test"}, []string{"minion"})
+
+	for _, minion := range []string{"node1", "node2", "node3"} {
+		vec.WithLabelValues(minion).Set(1)
+	}
+
+	if got := testutil.CollectAndCount(vec); got != 3 {
+		t.Errorf("Series should not be limited, got %d series", got)
+	}
+	if len(vec.limiter.series) != 0 {
+		t.Errorf("Series should not be tracked without limit")
+	}
+}
@@ -2,8 +2,13 @@ package metrics
 
 import (
 	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
 
 	"github.com/kpetremann/salt-exporter/pkg/event"
+	"github.com/kpetremann/salt-exporter/pkg/handler"
 	"github.com/rs/zerolog/log"
 )
 
@@ -15,12 +20,62 @@ func boolToFloat64(b bool) float64 {
 }
 
 func eventToMetrics(e event.SaltEvent, r *Registry) {
+	if e.Data.ID != "" {
+		r.UpdateMinionLastSeen(e.Data.ID, time.Now())
+	}
+
+	// key and auth events are sent by the master on behalf of the minion
+	if e.Module == event.KeyModule {
+		r.IncreaseKeyEventsTotal(e.Type)
+		return
+	}
+	if e.Module == event.AuthModule {
+		r.IncreaseMinionAuthTotal(e.Data.ID, e.Type)
+		return
+	}
+
+	// wheel functions and reactors are executed by the master
+	if e.Module == event.WheelModule {
+		if e.Type == "ret" {
+			success := e.Data.Retcode == 0 && (e.Data.Success == nil || *e.Data.Success)
+			r.IncreaseWheelFunctionTotal(e.Data.Fun, success)
+		}
+		return
+	}
+	if e.Module == event.ReactorModule {
+		r.IncreaseReactorExecutionsTotal(e.Data.SLS, e.Data.Success == nil || *e.Data.Success)
+		return
+	}
+
+	if e.Module == event.PresenceModule {
+		switch e.Type {
+		case "present":
+			r.UpdatePresence(e.Data.Present)
+		case "change":
+			r.UpdatePresenceChange(e.Data.New, e.Data.Lost)
+		}
+		return
+	}
+
 	// If we receive at least some response from the minion, we can consider it alive, regardless of its actual state
 	if e.Data.ID != "" {
 		r.UpdateEventLastResponse(e.Data.ID)
 	}
 
+	if e.Module == event.CustomModule {
+		r.UpdateCustomEvent(e.Tag, e.Data.ID, e.Data.Payload)
+		return
+	}
+
+	if e.Module == event.MinionModule {
+		if e.Type == "start" {
+			r.UpdateMinionStart(e.Data.ID)
+		}
+		return
+	}
+
 	if e.Module == event.BeaconModule {
+		r.UpdateBeaconMetrics(e.Type, e.Data.ID, e.Data.Payload)
 		if e.Type != "status" {
 			return
 		}
@@ -33,6 +88,10 @@ func eventToMetrics(e event.SaltEvent, r *Registry) {
 		state := e.ExtractState()
 		r.IncreaseNewJobTotal(e.Data.Fun, state)
 		r.IncreaseExpectedResponsesTotal(e.Data.Fun, state, float64(e.TargetNumber))
+		if e.Module == event.JobModule {
+			published, _ := e.ParseTimestamp()
+			r.TrackJob(e.Data.Jid, e.Data.Fun, state, e.Data.Minions, published)
+		}
 
 	case "ret":
 		//  for normal job, success field can be missing under certain conditions.
@@ -60,30 +119,132 @@ func eventToMetrics(e event.SaltEvent, r *Registry) {
 			r.IncreaseFunctionResponsesTotal(e.Data.Fun, state, e.Data.ID, success)
 		}
 
+		if e.Module == event.JobModule {
+			returned, _ := e.ParseTimestamp()
+			r.TrackJobResponse(e.Data.Jid, e.Data.ID, returned)
+		}
+
 		r.IncreaseResponseTotal(e.Data.ID, success)
 		r.SetFunctionStatus(e.Data.ID, e.Data.Fun, state, success)
+		r.IncreaseStateResultTotal(e.Data.ID, e.Data.Fun, e.StateResults)
+
+		if e.StateResults != nil {
+			changed, _ := e.CountStateChanges()
+			r.SetStateChanges(e.Data.ID, e.Data.Fun, state, e.IsTest, changed)
+			r.ObserveStateDurations(e.Data.ID, e.StateResults)
+		}
+
+		if e.IsOrchestration() {
+			r.SetOrchestrationResults(state, e.StateResults, e.StateDuration)
+		}
 
 		if e.StateDuration != nil {
-			r.SetJobDuration(e.Data.Fun, state, e.Data.ID, e.StateDuration.Seconds())
+			r.SetJobDuration(e.Data.Fun, state, e.Data.ID, legacyJobDuration(*e.StateDuration))
 		}
 	}
 }
 
-func ExposeMetrics(ctx context.Context, eventChan <-chan event.SaltEvent, watchChan <-chan event.WatchEvent, config Config) {
-	registry := NewRegistry(config)
+// legacyJobDuration returns the value of salt_job_duration_seconds.
+//
+// For backward compatibility, it is the sum of the Salt state durations which are in milliseconds,
+// as exposed by the first versions of the exporter.
+func legacyJobDuration(duration time.Duration) float64 {
+	return float64(duration) / float64(time.Millisecond)
+}
+
+// Handle implements handler.Handler.
+func (r *Registry) Handle(e event.SaltEvent) {
+	eventToMetrics(e, r)
+}
+
+// HandleWatch implements handler.WatchHandler.
+func (r *Registry) HandleWatch(e event.WatchEvent) {
+	if e.Op == event.Accepted {
+		r.AddKey(e.MinionName, e.State)
+	}
+	if e.Op == event.Removed {
+		r.DeleteKey(e.MinionName, e.State)
+	}
+
+	if e.State != event.KeyAccepted {
+		return
+	}
+	if e.Op == event.Accepted {
+		// accepted minions are considered disconnected until a presence event says otherwise
+		if _, known := r.connectedMinions[e.MinionName]; r.presenceReceived && !known {
+			r.setMinionConnected(e.MinionName, false, false)
+		}
+		if r.config.HealthMinions {
+			r.AddObservableMinion(e.MinionName)
+		}
+	}
+	if e.Op == event.Removed {
+		r.DeleteObservableMinion(e.MinionName)
+	}
+}
+
+// BuiltinHandler is the name of the handler exposing the built-in metrics.
+const BuiltinHandler = "builtin"
+
+// builtinLock serializes the registrations of the built-in handler.
+var builtinLock sync.Mutex
+
+// RegisterBuiltinHandler makes the built-in metrics available as a handler, using the provided configuration.
+//
+// It returns an error if a handler is already registered with the built-in name, i.e. when called twice.
+func RegisterBuiltinHandler(config Config) error {
+	builtinLock.Lock()
+	defer builtinLock.Unlock()
+
+	if slices.Contains(handler.Names(), BuiltinHandler) {
+		return fmt.Errorf("handler %s already registered", BuiltinHandler)
+	}
+
+	handler.Register(BuiltinHandler, func() (handler.Handler, error) {
+		return NewRegistry(config, nil)
+	})
+
+	return nil
+}
+
+// expiryInterval is how often the in-flight jobs and the stale series are checked for expiry.
+const expiryInterval = 10 * time.Second
+
+// expirer is implemented by the handlers forgetting their state after a delay.
+type expirer interface {
+	Expire(now time.Time)
+}
+
+// Expire implements expirer.
+func (r *Registry) Expire(now time.Time) {
+	r.ExpireJobs(now)
+	r.ExpireStaleMinions(now)
+}
+
+// ExposeMetrics dispatches the events to the handlers until the context is done.
+func ExposeMetrics(
+	ctx context.Context,
+	eventChan <-chan event.SaltEvent,
+	watchChan <-chan event.WatchEvent,
+	handlers []handler.Handler,
+	exporterMetrics *ExporterMetrics,
+	deduplicator *JobDeduplicator,
+	config Config,
+) {
+	expiryTicker := time.NewTicker(expiryInterval)
+	defer expiryTicker.Stop()
+
+	source := &sourceHandlers{handlers: handlers}
 
 	for {
 		select {
 		case <-ctx.Done():
 			log.Info().Msg("stopping event listener")
 			return
+		case now := <-expiryTicker.C:
+			source.expire(now)
 		case e := <-watchChan:
-			if e.Op == event.Accepted {
-				registry.AddObservableMinion(e.MinionName)
-			}
-			if e.Op == event.Removed {
-				registry.DeleteObservableMinion(e.MinionName)
-			}
+			source.handleWatch(e)
 		case e := <-eventChan:
 			if config.Global.Filters.IgnoreTest && e.IsTest {
 				continue
@@ -92,7 +253,18 @@ func ExposeMetrics(ctx context.Context, eventChan <-chan event.SaltEvent, watchC
 				continue
 			}
 
-			eventToMetrics(e, &registry)
+			// with multiple masters, the job returns are processed by the source which received the job
+			target := source
+			if deduplicator != nil {
+				var ok bool
+				if target, ok = deduplicator.route(e, source, time.Now()); !ok {
+					exporterMetrics.EventDuplicated()
+					continue
+				}
+			}
+
+			target.handle(e)
+			exporterMetrics.EventProcessed(e, time.Now())
 		}
 	}
 }
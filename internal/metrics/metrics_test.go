@@ -0,0 +1,649 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kpetremann/salt-exporter/pkg/event"
+	"github.com/kpetremann/salt-exporter/pkg/handler"
+	"github.com/kpetremann/salt-exporter/pkg/parser"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+func testConfig() Config {
+	var config Config
+	config.SaltNewJobTotal.Enabled = true
+	config.SaltExpectedResponsesTotal.Enabled = true
+	config.SaltFunctionResponsesTotal.Enabled = true
+	config.SaltScheduledJobReturnTotal.Enabled = true
+	config.SaltResponsesTotal.Enabled = true
+	config.SaltFunctionStatus.Enabled = true
+	config.SaltFunctionStatus.Filters.Functions = []string{"state.sls"}
+	config.SaltFunctionStatus.Filters.States = []string{"test"}
+	config.SaltOrchestrateStepResult.Enabled = true
+	config.SaltWheelFunctionTotal.Enabled = true
+	config.SaltReactorExecutionsTotal.Enabled = true
+	config.SaltKeyEventsTotal.Enabled = true
+	config.CustomEvents = []CustomEvent{{Tag: "myorg/deploy/*", Value: "duration"}}
+
+	return config
+}
+
+func stateSlsEvent(eventType string) event.SaltEvent {
+	return event.SaltEvent{
+		Tag:          "salt/job/20231009092021123456/" + eventType,
+		Type:         eventType,
+		Module:       event.JobModule,
+		TargetNumber: 2,
+		Data: event.EventData{
+			Fun:     "state.sls",
+			Arg:     []any{"test"},
+			ID:      "node1",
+			Jid:     "20231009092021123456",
+			Minions: []string{"node1", "node2"},
+			Success: new(true),
+		},
+	}
+}
+
+func TestEventToMetrics(t *testing.T) {
+	failedState := stateSlsEvent("ret")
+	failedState.StateModuleSuccess = new(false)
+
+	scheduled := stateSlsEvent("ret")
+	scheduled.IsScheduleJob = true
+
+	orchestration := event.SaltEvent{
+		Tag:    "salt/run/20231009121512345678/ret",
+		Type:   "ret",
+		Module: event.RunnerModule,
+		Data: event.EventData{
+			Fun:     "runner.state.orchestrate",
+			FunArgs: []any{"orch.deploy"},
+			ID:      "master",
+			Retcode: 1,
+		},
+		StateResults: []event.StateResult{
+			{ID: "salt_|-deploy_web_|-deploy_web_|-state", Name: "deploy_web", Result: new(true)},
+			{ID: "salt_|-restart_lb_|-restart_lb_|-function", Name: "restart_lb", Result: new(false)},
+		},
+	}
+
+	tests := []struct {
+		name   string
+		events []event.SaltEvent
+		metric func(r *Registry) prometheus.Collector
+		want   float64
+	}{
+		{
+			name:   "new job",
+			events: []event.SaltEvent{stateSlsEvent("new")},
+			metric: func(r *Registry) prometheus.Collector { return r.newJobTotal.WithLabelValues("state.sls", "test") },
+			want:   1,
+		},
+		{
+			name:   "expected responses",
+			events: []event.SaltEvent{stateSlsEvent("new"), stateSlsEvent("new")},
+			metric: func(r *Registry) prometheus.Collector {
+				return r.expectedResponsesTotal.WithLabelValues("state.sls", "test")
+			},
+			want: 4,
+		},
+		{
+			name:   "successful return",
+			events: []event.SaltEvent{stateSlsEvent("ret")},
+			metric: func(r *Registry) prometheus.Collector {
+				return r.functionResponsesTotal.WithLabelValues("state.sls", "test", "true")
+			},
+			want: 1,
+		},
+		{
+			name:   "failed state",
+			events: []event.SaltEvent{failedState},
+			metric: func(r *Registry) prometheus.Collector {
+				return r.functionStatus.WithLabelValues("node1", "state.sls", "test")
+			},
+			want: 0,
+		},
+		{
+			name:   "scheduled return",
+			events: []event.SaltEvent{scheduled},
+			metric: func(r *Registry) prometheus.Collector {
+				return r.scheduledJobReturnTotal.WithLabelValues("state.sls", "test", "true")
+			},
+			want: 1,
+		},
+		{
+			name:   "response total",
+			events: []event.SaltEvent{stateSlsEvent("ret"), failedState},
+			metric: func(r *Registry) prometheus.Collector { return r.responseTotal.WithLabelValues("node1", "false") },
+			want:   1,
+		},
+		{
+			name:   "orchestration step",
+			events: []event.SaltEvent{orchestration},
+			metric: func(r *Registry) prometheus.Collector {
+				return r.orchestrateStepResult.WithLabelValues("orch.deploy", "restart_lb")
+			},
+			want: 0,
+		},
+		{
+			name: "wheel return",
+			events: []event.SaltEvent{{
+				Tag:    "salt/wheel/20231009132011123456/ret",
+				Type:   "ret",
+				Module: event.WheelModule,
+				Data:   event.EventData{Fun: "wheel.key.accept", Success: new(true)},
+			}},
+			metric: func(r *Registry) prometheus.Collector {
+				return r.wheelFunctionTotal.WithLabelValues("wheel.key.accept", "true")
+			},
+			want: 1,
+		},
+		{
+			name: "reactor event",
+			events: []event.SaltEvent{
+				{
+					Tag:    "salt/reactor/fired",
+					Type:   "fired",
+					Module: event.ReactorModule,
+					Data:   event.EventData{SLS: "/srv/reactor/start.sls", Tag: "salt/minion/node1/start", Success: new(false)},
+				},
+				{
+					Tag:    "salt/reactor/fired",
+					Type:   "fired",
+					Module: event.ReactorModule,
+					Data:   event.EventData{SLS: "/srv/reactor/start.sls", Tag: "salt/minion/node2/start", Success: new(false)},
+				},
+			},
+			metric: func(r *Registry) prometheus.Collector {
+				return r.reactorExecutionsTotal.WithLabelValues("/srv/reactor/start.sls", "false")
+			},
+			want: 2,
+		},
+		{
+			name: "reactor event without success",
+			events: []event.SaltEvent{{
+				Tag:    "salt/reactor/fired",
+				Type:   "fired",
+				Module: event.ReactorModule,
+				Data:   event.EventData{SLS: "/srv/reactor/start.sls"},
+			}},
+			metric: func(r *Registry) prometheus.Collector {
+				return r.reactorExecutionsTotal.WithLabelValues("/srv/reactor/start.sls", "true")
+			},
+			want: 1,
+		},
+		{
+			name:   "key event",
+			events: []event.SaltEvent{{Tag: "salt/key", Type: "accept", Module: event.KeyModule}},
+			metric: func(r *Registry) prometheus.Collector { return r.keyEventsTotal.WithLabelValues("accept") },
+			want:   1,
+		},
+		{
+			name: "custom event",
+			events: []event.SaltEvent{{
+				Tag:    "myorg/deploy/finished",
+				Module: event.CustomModule,
+				Data:   event.EventData{ID: "node1", Payload: map[string]any{"duration": 42.5}},
+			}},
+			metric: func(r *Registry) prometheus.Collector {
+				return r.customEventValue.WithLabelValues("myorg/deploy/*", "node1")
+			},
+			want: 42.5,
+		},
+	}
+
+	for _, test := range tests {
+		r, err := NewRegistry(testConfig(), prometheus.NewRegistry())
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		for _, e := range test.events {
+			eventToMetrics(e, r)
+		}
+
+		if got := testutil.ToFloat64(test.metric(r)); got != test.want {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestNewRegistryAlreadyRegistered(t *testing.T) {
+	registerer := prometheus.NewRegistry()
+
+	if _, err := NewRegistry(testConfig(), registerer); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := NewRegistry(testConfig(), registerer); err == nil {
+		t.Errorf("Registering the metrics twice on the same registerer should fail")
+	}
+}
+
+func TestRegisterBuiltinHandler(t *testing.T) {
+	// the handlers are registered globally, the first call fails when the test is run several times
+	_ = RegisterBuiltinHandler(testConfig())
+	if err := RegisterBuiltinHandler(testConfig()); err == nil {
+		t.Errorf("Registering the built-in handler twice should fail")
+	}
+
+	if _, err := handler.New([]string{BuiltinHandler}, prometheus.NewRegistry()); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestExpireStaleMinions(t *testing.T) {
+	config := testConfig()
+	config.HealthMinions = true
+	config.Global.MinionSeriesTTL = time.Hour
+
+	r, err := NewRegistry(config, prometheus.NewRegistry())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	stale := stateSlsEvent("ret")
+	observed := stateSlsEvent("ret")
+	observed.Data.ID = "node2"
+
+	r.HandleWatch(event.WatchEvent{MinionName: "node2", Op: event.Accepted, State: event.KeyAccepted})
+	eventToMetrics(stale, r)
+	eventToMetrics(observed, r)
+
+	r.ExpireStaleMinions(time.Now())
+	if got := testutil.CollectAndCount(r.responseTotal); got != 2 {
+		t.Errorf("Series should not be expired before the TTL, got %d series", got)
+	}
+
+	r.ExpireStaleMinions(time.Now().Add(2 * time.Hour))
+	if got := testutil.ToFloat64(r.responseTotal.WithLabelValues("node2", "true")); got != 1 {
+		t.Errorf("Series of observed minions should be kept, got %v", got)
+	}
+	if got := testutil.CollectAndCount(r.responseTotal); got != 1 {
+		t.Errorf("Series of stale minions should be deleted, got %d series", got)
+	}
+	if got := testutil.CollectAndCount(r.eventLastResponse); got != 1 {
+		t.Errorf("Series of stale minions should be deleted, got %d series", got)
+	}
+}
+
+func TestDeleteMinionOnKeyRemoval(t *testing.T) {
+	r, err := NewRegistry(testConfig(), prometheus.NewRegistry())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	eventToMetrics(stateSlsEvent("ret"), r)
+	r.HandleWatch(event.WatchEvent{MinionName: "node1", Op: event.Removed, State: event.KeyAccepted})
+
+	for name, metric := range map[string]prometheus.Collector{
+		"salt_responses_total":                  r.responseTotal,
+		"salt_function_status":                  r.functionStatus,
+		"salt_responses_last_received_response": r.eventLastResponse,
+	} {
+		if got := testutil.CollectAndCount(metric); got != 0 {
+			t.Errorf("%s: unexpected series after key removal: %d", name, got)
+		}
+	}
+	if got := testutil.CollectAndCount(r.functionResponsesTotal); got != 1 {
+		t.Errorf("Series without minion label should be kept, got %d series", got)
+	}
+}
+
+func TestSaltKeys(t *testing.T) {
+	config := testConfig()
+	config.SaltKeys.Enabled = true
+
+	r, err := NewRegistry(config, prometheus.NewRegistry())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if got := testutil.CollectAndCount(r.keysTotal); got != 0 {
+		t.Errorf("salt_keys should not be exposed without PKI watcher, got %d series", got)
+	}
+
+	r.HandleWatch(event.WatchEvent{MinionName: "node1", Op: event.Accepted, State: event.KeyAccepted})
+	r.HandleWatch(event.WatchEvent{MinionName: "node2", Op: event.Accepted, State: event.KeyAccepted})
+	r.HandleWatch(event.WatchEvent{MinionName: "node3", Op: event.Accepted, State: event.KeyPending})
+	r.HandleWatch(event.WatchEvent{MinionName: "node3", Op: event.Removed, State: event.KeyPending})
+
+	if got := testutil.CollectAndCount(r.keysTotal); got != 4 {
+		t.Errorf("salt_keys should be exposed for all the states, got %d series", got)
+	}
+	for state, want := range map[event.KeyState]float64{
+		event.KeyAccepted: 2,
+		event.KeyPending:  0,
+		event.KeyRejected: 0,
+		event.KeyDenied:   0,
+	} {
+		if got := testutil.ToFloat64(r.keysTotal.WithLabelValues(state.String())); got != want {
+			t.Errorf("salt_keys{state=%q} = %v, want %v", state, got, want)
+		}
+	}
+}
+
+func TestMinionAuthAndStart(t *testing.T) {
+	events := []event.SaltEvent{
+		{Tag: "salt/auth", Type: "accept", Module: event.AuthModule, Data: event.EventData{ID: "node1"}},
+		{Tag: "salt/auth", Type: "accept", Module: event.AuthModule, Data: event.EventData{ID: "node2"}},
+		{Tag: "salt/auth", Type: "reject", Module: event.AuthModule, Data: event.EventData{ID: "node3"}},
+		{Tag: "salt/minion/node1/start", Type: "start", Module: event.MinionModule, Data: event.EventData{ID: "node1"}},
+		{Tag: "salt/minion/node2/start", Type: "start", Module: event.MinionModule, Data: event.EventData{ID: "node2"}},
+		{Tag: "salt/minion/node1/start", Type: "start", Module: event.MinionModule, Data: event.EventData{ID: "node1"}},
+	}
+
+	tests := []struct {
+		name           string
+		addMinionLabel bool
+		lastStart      bool
+		wantAuth       map[string]float64
+		wantStart      map[string]float64
+		wantLastStart  int
+	}{
+		{
+			name:      "without minion label",
+			wantAuth:  map[string]float64{"accept": 2, "reject": 1},
+			wantStart: map[string]float64{"": 3},
+		},
+		{
+			name:           "with minion label",
+			addMinionLabel: true,
+			lastStart:      true,
+			wantAuth:       map[string]float64{"node1/accept": 1, "node2/accept": 1, "node3/reject": 1},
+			wantStart:      map[string]float64{"node1": 2, "node2": 1},
+			wantLastStart:  2,
+		},
+	}
+
+	for _, test := range tests {
+		config := testConfig()
+		config.SaltMinionAuthTotal.Enabled = true
+		config.SaltMinionAuthTotal.AddMinionLabel = test.addMinionLabel
+		config.SaltMinionStartTotal.Enabled = true
+		config.SaltMinionStartTotal.AddMinionLabel = test.addMinionLabel
+		config.SaltMinionLastStart.Enabled = test.lastStart
+
+		r, err := NewRegistry(config, prometheus.NewRegistry())
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		for _, e := range events {
+			eventToMetrics(e, r)
+		}
+
+		if got := testutil.CollectAndCount(r.minionAuthTotal); got != len(test.wantAuth) {
+			t.Errorf("%s: salt_minion_auth_total: got %d series, want %d", test.name, got, len(test.wantAuth))
+		}
+		for labels, want := range test.wantAuth {
+			if got := testutil.ToFloat64(r.minionAuthTotal.WithLabelValues(strings.Split(labels, "/")...)); got != want {
+				t.Errorf("%s: salt_minion_auth_total{%s} = %v, want %v", test.name, labels, got, want)
+			}
+		}
+
+		if got := testutil.CollectAndCount(r.minionStartTotal); got != len(test.wantStart) {
+			t.Errorf("%s: salt_minion_start_total: got %d series, want %d", test.name, got, len(test.wantStart))
+		}
+		for minion, want := range test.wantStart {
+			var labels []string
+			if minion != "" {
+				labels = append(labels, minion)
+			}
+			if got := testutil.ToFloat64(r.minionStartTotal.WithLabelValues(labels...)); got != want {
+				t.Errorf("%s: salt_minion_start_total{%s} = %v, want %v", test.name, minion, got, want)
+			}
+		}
+
+		if got := testutil.CollectAndCount(r.minionLastStart); got != test.wantLastStart {
+			t.Errorf("%s: salt_minion_last_start: got %d series, want %d", test.name, got, test.wantLastStart)
+		}
+	}
+}
+
+func TestMinionPresence(t *testing.T) {
+	accept := func(minion string) func(r *Registry) {
+		return func(r *Registry) {
+			r.HandleWatch(event.WatchEvent{MinionName: minion, Op: event.Accepted, State: event.KeyAccepted})
+		}
+	}
+	present := func(minions ...string) func(r *Registry) {
+		return func(r *Registry) { r.UpdatePresence(minions) }
+	}
+	change := func(connected, lost []string) func(r *Registry) {
+		return func(r *Registry) { r.UpdatePresenceChange(connected, lost) }
+	}
+
+	tests := []struct {
+		name           string
+		steps          []func(r *Registry)
+		wantConnected  map[string]float64
+		wantConnect    float64
+		wantDisconnect float64
+	}{
+		{
+			name:          "accepted minion not present",
+			steps:         []func(r *Registry){accept("node1"), accept("node2"), present("node1")},
+			wantConnected: map[string]float64{"node1": 1, "node2": 0},
+		},
+		{
+			name:          "key accepted after the presence",
+			steps:         []func(r *Registry){present("node1"), accept("node2")},
+			wantConnected: map[string]float64{"node1": 1, "node2": 0},
+		},
+		{
+			name:           "present then missing",
+			steps:          []func(r *Registry){accept("node1"), present("node1"), present()},
+			wantConnected:  map[string]float64{"node1": 0},
+			wantDisconnect: 1,
+		},
+		{
+			name:          "not present then present",
+			steps:         []func(r *Registry){accept("node1"), present(), present("node1")},
+			wantConnected: map[string]float64{"node1": 1},
+			wantConnect:   1,
+		},
+		{
+			name:          "change before the presence",
+			steps:         []func(r *Registry){change([]string{"node1"}, nil), present("node1")},
+			wantConnected: map[string]float64{"node1": 1},
+			wantConnect:   1,
+		},
+		{
+			name:           "lost before the presence",
+			steps:          []func(r *Registry){accept("node1"), change(nil, []string{"node1"}), present()},
+			wantConnected:  map[string]float64{"node1": 0},
+			wantDisconnect: 1,
+		},
+		{
+			name:          "change of a present minion",
+			steps:         []func(r *Registry){present("node1"), change([]string{"node1"}, nil)},
+			wantConnected: map[string]float64{"node1": 1},
+		},
+		{
+			name:           "reconnection",
+			steps:          []func(r *Registry){present("node1"), change(nil, []string{"node1"}), change([]string{"node1"}, nil)},
+			wantConnected:  map[string]float64{"node1": 1},
+			wantConnect:    1,
+			wantDisconnect: 1,
+		},
+	}
+
+	for _, test := range tests {
+		config := testConfig()
+		config.SaltMinionConnected.Enabled = true
+		config.SaltMinionConnectTotal.Enabled = true
+		config.SaltMinionDisconnectTotal.Enabled = true
+
+		r, err := NewRegistry(config, prometheus.NewRegistry())
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		for _, step := range test.steps {
+			step(r)
+		}
+
+		if got := testutil.CollectAndCount(r.minionConnected); got != len(test.wantConnected) {
+			t.Errorf("%s: salt_minion_connected: got %d series, want %d", test.name, got, len(test.wantConnected))
+		}
+		for minion, want := range test.wantConnected {
+			if got := testutil.ToFloat64(r.minionConnected.WithLabelValues(minion)); got != want {
+				t.Errorf("%s: salt_minion_connected{minion=%q} = %v, want %v", test.name, minion, got, want)
+			}
+		}
+		if got := testutil.ToFloat64(r.minionConnectTotal.WithLabelValues()); got != test.wantConnect {
+			t.Errorf("%s: salt_minion_connect_total = %v, want %v", test.name, got, test.wantConnect)
+		}
+		if got := testutil.ToFloat64(r.minionDisconnectTotal.WithLabelValues()); got != test.wantDisconnect {
+			t.Errorf("%s: salt_minion_disconnect_total = %v, want %v", test.name, got, test.wantDisconnect)
+		}
+	}
+}
+
+func TestExpireJobsLatencyOnly(t *testing.T) {
+	config := testConfig()
+	config.SaltJobResponseLatencySeconds.Enabled = true
+	config.SaltJobMissingResponsesTotal.Timeout = time.Minute
+
+	r, err := NewRegistry(config, prometheus.NewRegistry())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	eventToMetrics(stateSlsEvent("new"), r)
+	eventToMetrics(stateSlsEvent("ret"), r)
+
+	r.ExpireJobs(time.Now())
+	if len(r.jobs.jobs) != 1 {
+		t.Errorf("Job should be tracked until the timeout, got %d jobs", len(r.jobs.jobs))
+	}
+
+	r.ExpireJobs(time.Now().Add(2 * time.Minute))
+	if len(r.jobs.jobs) != 0 {
+		t.Errorf("Job without response should be forgotten after the timeout, got %d jobs", len(r.jobs.jobs))
+	}
+	if got := testutil.CollectAndCount(r.jobMissingResponsesTotal); got != 0 {
+		t.Errorf("Missing responses should not be counted when disabled, got %d series", got)
+	}
+}
+
+func TestJobDurationType(t *testing.T) {
+	tests := []struct {
+		jobDurationType string
+		wantType        dto.MetricType
+		wantNative      bool
+	}{
+		{jobDurationType: GaugeType, wantType: dto.MetricType_GAUGE},
+		{jobDurationType: HistogramType, wantType: dto.MetricType_HISTOGRAM},
+		{jobDurationType: NativeHistogramType, wantType: dto.MetricType_HISTOGRAM, wantNative: true},
+	}
+
+	for _, test := range tests {
+		config := testConfig()
+		config.SaltJobDurationSeconds.Enabled = true
+		config.SaltJobDurationSeconds.Type = test.jobDurationType
+		config.SaltJobDurationSeconds.Buckets = []float64{1, 10, 100}
+
+		registry := prometheus.NewRegistry()
+		r, err := NewRegistry(config, registry)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		r.SetJobDuration("state.sls", "test", "node1", 5)
+
+		families, err := registry.Gather()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		var family *dto.MetricFamily
+		for _, f := range families {
+			if f.GetName() == "salt_job_duration_seconds" {
+				family = f
+			}
+		}
+		if family == nil {
+			t.Fatalf("%s: salt_job_duration_seconds not exposed", test.jobDurationType)
+		}
+		if family.GetType() != test.wantType || len(family.GetMetric()) != 1 {
+			t.Fatalf("%s: got %d series of type %s, want 1 series of type %s",
+				test.jobDurationType, len(family.GetMetric()), family.GetType(), test.wantType)
+		}
+
+		if test.wantType != dto.MetricType_HISTOGRAM {
+			continue
+		}
+		histogram := family.GetMetric()[0].GetHistogram()
+		if histogram.GetSampleCount() != 1 || histogram.GetSampleSum() != 5 {
+			t.Errorf("%s: got %d samples summing to %v, want 1 sample of 5",
+				test.jobDurationType, histogram.GetSampleCount(), histogram.GetSampleSum())
+		}
+		if native := len(histogram.GetPositiveSpan()) > 0; native != test.wantNative {
+			t.Errorf("%s: native buckets = %t, want %t", test.jobDurationType, native, test.wantNative)
+		}
+	}
+}
+
+func TestOrchestrationDurations(t *testing.T) {
+	config := testConfig()
+	config.SaltOrchestrateStepDurationSeconds.Enabled = true
+	config.SaltOrchestrateDurationSeconds.Enabled = true
+
+	r, err := NewRegistry(config, prometheus.NewRegistry())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Salt reports the duration of the steps in milliseconds
+	body, err := msgpack.Marshal(map[string]any{
+		"_stamp":   "2023-10-09T12:15:30.123456",
+		"fun":      "runner.state.orchestrate",
+		"fun_args": []any{"orch.deploy"},
+		"jid":      "20231009121512345678",
+		"return": map[string]any{
+			"data": map[string]any{
+				"master1_master": map[string]any{
+					"salt_|-deploy_web_|-deploy_web_|-state": map[string]any{
+						"__id__": "deploy_web", "__sls__": "orch.deploy", "name": "deploy_web", "result": true, "duration": 12.5,
+					},
+					"salt_|-restart_lb_|-restart_lb_|-function": map[string]any{
+						"__id__": "restart_lb", "__sls__": "orch.deploy", "name": "service.restart", "result": false, "duration": 1.5,
+					},
+				},
+			},
+			"retcode": 1,
+		},
+		"success": false,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	e, err := parser.NewEventParser(false).Parse(map[string]any{"body": append([]byte("salt/run/20231009121512345678/ret\n\n"), body...)})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	eventToMetrics(e, r)
+
+	tests := []struct {
+		name   string
+		metric prometheus.Collector
+		want   float64
+	}{
+		{name: "deploy_web step", metric: r.orchestrateStepDurationSeconds.WithLabelValues("orch.deploy", "deploy_web"), want: 0.0125},
+		{name: "restart_lb step", metric: r.orchestrateStepDurationSeconds.WithLabelValues("orch.deploy", "restart_lb"), want: 0.0015},
+		{name: "orchestration", metric: r.orchestrateDurationSeconds.WithLabelValues("orch.deploy"), want: 0.014},
+	}
+
+	for _, test := range tests {
+		if got := testutil.ToFloat64(test.metric); got != test.want {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
@@ -2,35 +2,83 @@ package metrics
 
 import (
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/kpetremann/salt-exporter/internal/filters"
+	"github.com/kpetremann/salt-exporter/pkg/event"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promauto"
 )
 
 type Registry struct {
 	config Config
+	limits seriesLimits
 
-	observedMinions int32
+	observedMinions map[string]struct{}
+	// minionsLastSeen is the time of the last event of each minion, only tracked if the series TTL is enabled
+	minionsLastSeen map[string]time.Time
 
-	newJobTotal            *prometheus.CounterVec
-	expectedResponsesTotal *prometheus.CounterVec
+	newJobTotal            *limitedCounterVec
+	expectedResponsesTotal *limitedCounterVec
 
-	functionResponsesTotal  *prometheus.CounterVec
-	scheduledJobReturnTotal *prometheus.CounterVec
+	functionResponsesTotal  *limitedCounterVec
+	scheduledJobReturnTotal *limitedCounterVec
 
-	responseTotal  *prometheus.CounterVec
-	functionStatus *prometheus.GaugeVec
+	responseTotal  *limitedCounterVec
+	functionStatus *limitedGaugeVec
 
-	statusLastResponse *prometheus.GaugeVec
-	eventLastResponse  *prometheus.GaugeVec
-	minionsTotal       *prometheus.GaugeVec
+	statusLastResponse *limitedGaugeVec
+	eventLastResponse  *limitedGaugeVec
+	minionsTotal       *limitedGaugeVec
 
-	jobDurationSeconds *prometheus.GaugeVec
+	jobDurationSeconds   *limitedGaugeVec
+	jobDurationHistogram *limitedHistogramVec
+
+	stateResultTotal  *limitedCounterVec
+	stateChanges      *limitedGaugeVec
+	stateChangesTotal *limitedCounterVec
+
+	stateDurationSeconds        *limitedHistogramVec
+	stateSlowestDurationSeconds *limitedGaugeVec
+
+	orchestrateStepResult          *limitedGaugeVec
+	orchestrateStepDurationSeconds *limitedGaugeVec
+	orchestrateDurationSeconds     *limitedGaugeVec
+
+	wheelFunctionTotal     *limitedCounterVec
+	reactorExecutionsTotal *limitedCounterVec
+
+	keyEventsTotal *limitedCounterVec
+	keys           map[event.KeyState]map[string]struct{}
+	keysTotal      *limitedGaugeVec
+	// keysSeeded is true once the first key is received from a PKI watcher
+	keysSeeded bool
+
+	minionAuthTotal  *limitedCounterVec
+	minionStartTotal *limitedCounterVec
+	minionLastStart  *limitedGaugeVec
+
+	// presenceReceived is true once the master sent its first presence event
+	presenceReceived      bool
+	connectedMinions      map[string]bool
+	minionConnected       *limitedGaugeVec
+	minionConnectTotal    *limitedCounterVec
+	minionDisconnectTotal *limitedCounterVec
+
+	beaconGauges []beaconGauge
+
+	customEventsTotal *limitedCounterVec
+	customEventValue  *limitedGaugeVec
+
+	jobs                     jobTracker
+	jobMissingResponsesTotal *limitedCounterVec
+	jobResponseLatency       *limitedHistogramVec
 }
 
-func NewRegistry(config Config) Registry {
+// NewRegistry creates the built-in metrics and registers them on the registerer.
+//
+// If registerer is nil, the metrics are not registered, i.e. when registered later as a handler.
+func NewRegistry(config Config, registerer prometheus.Registerer) (*Registry, error) {
 	functionResponsesTotalLabels := []string{"function", "state", "success"}
 	if config.SaltFunctionResponsesTotal.AddMinionLabel {
 		functionResponsesTotalLabels = append([]string{"minion"}, functionResponsesTotalLabels...)
@@ -46,11 +94,55 @@ func NewRegistry(config Config) Registry {
 		jobDurationSecondsLabels = append([]string{"minion"}, jobDurationSecondsLabels...)
 	}
 
-	return Registry{
+	jobResponseLatencyLabels := []string{"function", "state"}
+	if config.SaltJobResponseLatencySeconds.AddMinionLabel {
+		jobResponseLatencyLabels = append([]string{"minion"}, jobResponseLatencyLabels...)
+	}
+
+	stateChangesTotalLabels := []string{"function", "state", "test"}
+	if config.SaltStateChangesTotal.AddMinionLabel {
+		stateChangesTotalLabels = append([]string{"minion"}, stateChangesTotalLabels...)
+	}
+
+	stateDurationSecondsLabels := []string{"sls", "state_id"}
+	if config.SaltStateDurationSeconds.AddMinionLabel {
+		stateDurationSecondsLabels = append([]string{"minion"}, stateDurationSecondsLabels...)
+	}
+
+	stateSlowestDurationSecondsLabels := []string{"sls", "state_id", "rank"}
+	if config.SaltStateSlowestDurationSeconds.AddMinionLabel {
+		stateSlowestDurationSecondsLabels = append([]string{"minion"}, stateSlowestDurationSecondsLabels...)
+	}
+
+	minionAuthTotalLabels := []string{"result"}
+	if config.SaltMinionAuthTotal.AddMinionLabel {
+		minionAuthTotalLabels = append([]string{"minion"}, minionAuthTotalLabels...)
+	}
+
+	var minionStartTotalLabels []string
+	if config.SaltMinionStartTotal.AddMinionLabel {
+		minionStartTotalLabels = []string{"minion"}
+	}
+
+	var minionConnectTotalLabels []string
+	if config.SaltMinionConnectTotal.AddMinionLabel {
+		minionConnectTotalLabels = []string{"minion"}
+	}
+
+	var minionDisconnectTotalLabels []string
+	if config.SaltMinionDisconnectTotal.AddMinionLabel {
+		minionDisconnectTotalLabels = []string{"minion"}
+	}
+
+	limits := newSeriesLimits(config.Global.MaxSeriesPerMetric)
+
+	r := &Registry{
 		config: config,
+		limits: limits,
 
-		observedMinions: 0,
-		newJobTotal: promauto.NewCounterVec(
+		observedMinions: make(map[string]struct{}),
+		minionsLastSeen: make(map[string]time.Time),
+		newJobTotal: limits.newCounterVec(
 			prometheus.CounterOpts{
 				Name: "salt_new_job_total",
 				Help: "Total number of new jobs processed",
@@ -58,7 +150,7 @@ func NewRegistry(config Config) Registry {
 			[]string{"function", "state"},
 		),
 
-		expectedResponsesTotal: promauto.NewCounterVec(
+		expectedResponsesTotal: limits.newCounterVec(
 			prometheus.CounterOpts{
 				Name: "salt_expected_responses_total",
 				Help: "Total number of expected minions responses",
@@ -66,7 +158,7 @@ func NewRegistry(config Config) Registry {
 			[]string{"function", "state"},
 		),
 
-		functionResponsesTotal: promauto.NewCounterVec(
+		functionResponsesTotal: limits.newCounterVec(
 			prometheus.CounterOpts{
 				Name: "salt_function_responses_total",
 				Help: "Total number of responses per function processed",
@@ -74,7 +166,7 @@ func NewRegistry(config Config) Registry {
 			functionResponsesTotalLabels,
 		),
 
-		scheduledJobReturnTotal: promauto.NewCounterVec(
+		scheduledJobReturnTotal: limits.newCounterVec(
 			prometheus.CounterOpts{
 				Name: "salt_scheduled_job_return_total",
 				Help: "Total number of scheduled job responses",
@@ -82,7 +174,7 @@ func NewRegistry(config Config) Registry {
 			scheduledJobReturnTotalLabels,
 		),
 
-		responseTotal: promauto.NewCounterVec(
+		responseTotal: limits.newCounterVec(
 			prometheus.CounterOpts{
 				Name: "salt_responses_total",
 				Help: "Total number of responses",
@@ -90,41 +182,300 @@ func NewRegistry(config Config) Registry {
 			[]string{"minion", "success"},
 		),
 
-		functionStatus: promauto.NewGaugeVec(
+		functionStatus: limits.newGaugeVec(
 			prometheus.GaugeOpts{
 				Name: "salt_function_status",
 				Help: "Last function/state success, 0=Failed, 1=Success",
 			},
 			[]string{"minion", "function", "state"},
 		),
-		statusLastResponse: promauto.NewGaugeVec(
+		statusLastResponse: limits.newGaugeVec(
 			prometheus.GaugeOpts{
 				Name: "salt_health_last_heartbeat",
 				Help: "Last status beacon received. Unix timestamp",
 			},
 			[]string{"minion"},
 		),
-		eventLastResponse: promauto.NewGaugeVec(
+		eventLastResponse: limits.newGaugeVec(
 			prometheus.GaugeOpts{
 				Name: "salt_responses_last_received_response",
 				Help: "Last event received from minion, Unix timestamp",
 			},
 			[]string{"minion"},
 		),
-		minionsTotal: promauto.NewGaugeVec(
+		minionsTotal: limits.newGaugeVec(
 			prometheus.GaugeOpts{
 				Name: "salt_health_minions_total",
 				Help: "Total number of observed minions via status beacon",
 			}, []string{},
 		),
 
-		jobDurationSeconds: promauto.NewGaugeVec(
+		stateResultTotal: limits.newCounterVec(
+			prometheus.CounterOpts{
+				Name: "salt_state_result_total",
+				Help: "Total number of state executions per state ID and result",
+			},
+			[]string{"minion", "sls", "state_id", "function", "result"},
+		),
+		stateChanges: limits.newGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "salt_state_changes",
+				Help: "Number of states with changes during the last run",
+			},
+			[]string{"minion", "function", "state", "test"},
+		),
+		stateChangesTotal: limits.newCounterVec(
+			prometheus.CounterOpts{
+				Name: "salt_state_changes_total",
+				Help: "Total number of states with changes",
+			},
+			stateChangesTotalLabels,
+		),
+		stateDurationSeconds: limits.newHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "salt_state_duration_seconds",
+				Help:    "Duration of each state in seconds",
+				Buckets: config.SaltStateDurationSeconds.Buckets,
+			},
+			stateDurationSecondsLabels,
+		),
+		stateSlowestDurationSeconds: limits.newGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "salt_state_slowest_duration_seconds",
+				Help: "Duration of the slowest states per SLS during the last run in seconds",
+			},
+			stateSlowestDurationSecondsLabels,
+		),
+
+		orchestrateStepResult: limits.newGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "salt_orchestrate_step_result",
+				Help: "Result of each orchestration step during the last run, 0=Failed, 1=Success",
+			},
+			[]string{"orchestration", "step"},
+		),
+		orchestrateStepDurationSeconds: limits.newGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "salt_orchestrate_step_duration_seconds",
+				Help: "Duration of each orchestration step during the last run in seconds",
+			},
+			[]string{"orchestration", "step"},
+		),
+		orchestrateDurationSeconds: limits.newGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "salt_orchestrate_duration_seconds",
+				Help: "Duration of the last orchestration run in seconds",
+			},
+			[]string{"orchestration"},
+		),
+
+		wheelFunctionTotal: limits.newCounterVec(
+			prometheus.CounterOpts{
+				Name: "salt_wheel_function_total",
+				Help: "Total number of wheel function calls per function and success",
+			},
+			[]string{"function", "success"},
+		),
+		reactorExecutionsTotal: limits.newCounterVec(
+			prometheus.CounterOpts{
+				Name: "salt_reactor_executions_total",
+				Help: "Total number of reactor executions per reactor SLS and success",
+			},
+			[]string{"sls", "success"},
+		),
+
+		keyEventsTotal: limits.newCounterVec(
+			prometheus.CounterOpts{
+				Name: "salt_key_events_total",
+				Help: "Total number of key events per action",
+			},
+			[]string{"action"},
+		),
+		keys: make(map[event.KeyState]map[string]struct{}),
+		keysTotal: limits.newGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "salt_keys",
+				Help: "Number of minion keys per state",
+			},
+			[]string{"state"},
+		),
+
+		minionAuthTotal: limits.newCounterVec(
+			prometheus.CounterOpts{
+				Name: "salt_minion_auth_total",
+				Help: "Total number of minion authentications per result",
+			},
+			minionAuthTotalLabels,
+		),
+		minionStartTotal: limits.newCounterVec(
+			prometheus.CounterOpts{
+				Name: "salt_minion_start_total",
+				Help: "Total number of minion starts",
+			},
+			minionStartTotalLabels,
+		),
+		minionLastStart: limits.newGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "salt_minion_last_start",
+				Help: "Last minion start, Unix timestamp",
+			},
+			[]string{"minion"},
+		),
+
+		connectedMinions: make(map[string]bool),
+		minionConnected: limits.newGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "salt_minion_connected",
+				Help: "Minion connection to the master based on presence events, 0=Disconnected, 1=Connected",
+			},
+			[]string{"minion"},
+		),
+		minionConnectTotal: limits.newCounterVec(
+			prometheus.CounterOpts{
+				Name: "salt_minion_connect_total",
+				Help: "Total number of minion connections based on presence events",
+			},
+			minionConnectTotalLabels,
+		),
+		minionDisconnectTotal: limits.newCounterVec(
+			prometheus.CounterOpts{
+				Name: "salt_minion_disconnect_total",
+				Help: "Total number of minion disconnections based on presence events",
+			},
+			minionDisconnectTotalLabels,
+		),
+
+		customEventsTotal: limits.newCounterVec(
+			prometheus.CounterOpts{
+				Name: "salt_custom_events_total",
+				Help: "Total number of custom events per configured tag pattern",
+			},
+			[]string{"tag_pattern"},
+		),
+		customEventValue: limits.newGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "salt_custom_event_value",
+				Help: "Last value extracted from the custom events per configured tag pattern",
+			},
+			[]string{"tag_pattern", "minion"},
+		),
+
+		jobs: newJobTracker(),
+		jobMissingResponsesTotal: limits.newCounterVec(
+			prometheus.CounterOpts{
+				Name: "salt_job_missing_responses_total",
+				Help: "Total number of minions which did not return before the job timeout",
+			},
+			[]string{"function", "state", "minion"},
+		),
+		jobResponseLatency: limits.newHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "salt_job_response_latency_seconds",
+				Help:    "Latency between the publication of a job and the minion response in seconds",
+				Buckets: config.SaltJobResponseLatencySeconds.Buckets,
+			},
+			jobResponseLatencyLabels,
+		),
+	}
+
+	for _, beacon := range config.Beacons {
+		r.beaconGauges = append(r.beaconGauges, newBeaconGauge(beacon, limits))
+	}
+
+	switch config.SaltJobDurationSeconds.Type {
+	case HistogramType, NativeHistogramType:
+		opts := prometheus.HistogramOpts{
+			Name:    "salt_job_duration_seconds",
+			Help:    "Duration of Salt jobs in seconds",
+			Buckets: config.SaltJobDurationSeconds.Buckets,
+		}
+		if config.SaltJobDurationSeconds.Type == NativeHistogramType {
+			opts.NativeHistogramBucketFactor = 1.1
+			opts.NativeHistogramMaxBucketNumber = 100
+			opts.NativeHistogramMinResetDuration = time.Hour
+		}
+		r.jobDurationHistogram = limits.newHistogramVec(opts, jobDurationSecondsLabels)
+	default:
+		r.jobDurationSeconds = limits.newGaugeVec(
 			prometheus.GaugeOpts{
 				Name: "salt_job_duration_seconds",
 				Help: "Last duration of a Salt job in seconds",
 			},
 			jobDurationSecondsLabels,
-		),
+		)
+	}
+
+	if registerer != nil {
+		if err := registerer.Register(r); err != nil {
+			return nil, err
+		}
+	}
+
+	return r, nil
+}
+
+// collectors returns all the metrics of the registry.
+func (r *Registry) collectors() []prometheus.Collector {
+	collectors := []prometheus.Collector{
+		r.limits.dropped,
+		r.newJobTotal,
+		r.expectedResponsesTotal,
+		r.functionResponsesTotal,
+		r.scheduledJobReturnTotal,
+		r.responseTotal,
+		r.functionStatus,
+		r.statusLastResponse,
+		r.eventLastResponse,
+		r.minionsTotal,
+		r.stateResultTotal,
+		r.stateChanges,
+		r.stateChangesTotal,
+		r.stateDurationSeconds,
+		r.stateSlowestDurationSeconds,
+		r.orchestrateStepResult,
+		r.orchestrateStepDurationSeconds,
+		r.orchestrateDurationSeconds,
+		r.wheelFunctionTotal,
+		r.reactorExecutionsTotal,
+		r.keyEventsTotal,
+		r.keysTotal,
+		r.minionAuthTotal,
+		r.minionStartTotal,
+		r.minionLastStart,
+		r.minionConnected,
+		r.minionConnectTotal,
+		r.minionDisconnectTotal,
+		r.customEventsTotal,
+		r.customEventValue,
+		r.jobMissingResponsesTotal,
+		r.jobResponseLatency,
+	}
+
+	// only one of them is created depending on the configured type
+	if r.jobDurationHistogram != nil {
+		collectors = append(collectors, r.jobDurationHistogram)
+	} else {
+		collectors = append(collectors, r.jobDurationSeconds)
+	}
+
+	for _, b := range r.beaconGauges {
+		collectors = append(collectors, b.gauge)
+	}
+
+	return collectors
+}
+
+// Describe implements prometheus.Collector.
+func (r *Registry) Describe(ch chan<- *prometheus.Desc) {
+	for _, c := range r.collectors() {
+		c.Describe(ch)
+	}
+}
+
+// Collect implements prometheus.Collector.
+func (r *Registry) Collect(ch chan<- prometheus.Metric) {
+	for _, c := range r.collectors() {
+		c.Collect(ch)
 	}
 }
 
@@ -139,16 +490,65 @@ func (r *Registry) UpdateEventLastResponse(minion string) {
 }
 
 func (r *Registry) AddObservableMinion(minion string) {
-	r.observedMinions += 1
+	r.observedMinions[minion] = struct{}{}
 	r.UpdateLastHeartbeat(minion)
-	r.minionsTotal.WithLabelValues().Set(float64(r.observedMinions))
+	r.minionsTotal.WithLabelValues().Set(float64(len(r.observedMinions)))
 }
 
+// DeleteObservableMinion deletes all the series of the minion, i.e. when its key is removed.
 func (r *Registry) DeleteObservableMinion(minion string) {
-	r.statusLastResponse.DeleteLabelValues(minion)
-	r.eventLastResponse.DeleteLabelValues(minion)
-	r.observedMinions -= 1
-	r.minionsTotal.WithLabelValues().Set(float64(r.observedMinions))
+	r.deleteMinionSeries(minion)
+
+	if _, ok := r.observedMinions[minion]; ok {
+		delete(r.observedMinions, minion)
+		r.minionsTotal.WithLabelValues().Set(float64(len(r.observedMinions)))
+	}
+}
+
+// deleteMinionSeries deletes the series having the minion label from all the metrics.
+func (r *Registry) deleteMinionSeries(minion string) {
+	labels := prometheus.Labels{"minion": minion}
+	for _, c := range r.collectors() {
+		// the metrics without minion label are left untouched
+		if vec, ok := c.(interface{ DeletePartialMatch(prometheus.Labels) int }); ok {
+			vec.DeletePartialMatch(labels)
+		}
+	}
+
+	delete(r.connectedMinions, minion)
+	delete(r.minionsLastSeen, minion)
+}
+
+func (r *Registry) minionSeriesTTLEnabled() bool {
+	return r.config.Global.MinionSeriesTTL > 0
+}
+
+// UpdateMinionLastSeen records the last event of the minion for the series TTL.
+func (r *Registry) UpdateMinionLastSeen(minion string, now time.Time) {
+	if r.minionSeriesTTLEnabled() {
+		r.minionsLastSeen[minion] = now
+	}
+}
+
+// ExpireStaleMinions deletes the series of the minions without events since the TTL.
+//
+// The observed minions are kept, their series are needed to detect dead minions.
+func (r *Registry) ExpireStaleMinions(now time.Time) {
+	if !r.minionSeriesTTLEnabled() {
+		return
+	}
+
+	for minion, lastSeen := range r.minionsLastSeen {
+		if now.Sub(lastSeen) < r.config.Global.MinionSeriesTTL {
+			continue
+		}
+		if _, observed := r.observedMinions[minion]; observed {
+			delete(r.minionsLastSeen, minion)
+			continue
+		}
+
+		r.deleteMinionSeries(minion)
+	}
 }
 
 func (r *Registry) IncreaseNewJobTotal(function, state string) {
@@ -197,7 +597,11 @@ func (r *Registry) SetJobDuration(function, state, minion string, durationSecond
 		if r.config.SaltJobDurationSeconds.AddMinionLabel {
 			labels = append([]string{minion}, labels...)
 		}
-		r.jobDurationSeconds.WithLabelValues(labels...).Set(durationSeconds)
+		if r.jobDurationHistogram != nil {
+			r.jobDurationHistogram.WithLabelValues(labels...).Observe(durationSeconds)
+		} else {
+			r.jobDurationSeconds.WithLabelValues(labels...).Set(durationSeconds)
+		}
 	}
 }
 
@@ -214,3 +618,336 @@ func (r *Registry) SetFunctionStatus(minion, function, state string, success boo
 
 	r.functionStatus.WithLabelValues(minion, function, state).Set(boolToFloat64(success))
 }
+
+func (r *Registry) IncreaseStateResultTotal(minion, function string, states []event.StateResult) {
+	if !r.config.SaltStateResultTotal.Enabled {
+		return
+	}
+
+	include := r.config.SaltStateResultTotal.Filters.Include
+	exclude := r.config.SaltStateResultTotal.Filters.Exclude
+	for _, state := range states {
+		if len(include) > 0 && !filters.Match(state.ID, include) {
+			continue
+		}
+		if filters.Match(state.ID, exclude) {
+			continue
+		}
+		r.stateResultTotal.WithLabelValues(minion, state.SLS, state.ID, function, state.Outcome()).Inc()
+	}
+}
+
+func (r *Registry) SetStateChanges(minion, function, state string, test bool, changed int) {
+	isTest := strconv.FormatBool(test)
+
+	if r.config.SaltStateChanges.Enabled {
+		r.stateChanges.WithLabelValues(minion, function, state, isTest).Set(float64(changed))
+	}
+
+	if r.config.SaltStateChangesTotal.Enabled {
+		labels := []string{function, state, isTest}
+		if r.config.SaltStateChangesTotal.AddMinionLabel {
+			labels = append([]string{minion}, labels...)
+		}
+		r.stateChangesTotal.WithLabelValues(labels...).Add(float64(changed))
+	}
+}
+
+func (r *Registry) ObserveStateDurations(minion string, states []event.StateResult) {
+	if r.config.SaltStateDurationSeconds.Enabled {
+		for _, state := range states {
+			labels := []string{state.SLS, state.ID}
+			if r.config.SaltStateDurationSeconds.AddMinionLabel {
+				labels = append([]string{minion}, labels...)
+			}
+			r.stateDurationSeconds.WithLabelValues(labels...).Observe(state.Duration.Seconds())
+		}
+	}
+
+	if r.config.SaltStateSlowestDurationSeconds.Enabled {
+		addMinion := r.config.SaltStateSlowestDurationSeconds.AddMinionLabel
+
+		for sls, slowest := range slowestStatesBySLS(states, r.config.SaltStateSlowestDurationSeconds.Top) {
+			// the previous run of the SLS may have had other slowest states
+			previous := prometheus.Labels{"sls": sls}
+			if addMinion {
+				previous["minion"] = minion
+			}
+			r.stateSlowestDurationSeconds.DeletePartialMatch(previous)
+
+			for rank, state := range slowest {
+				labels := []string{sls, state.ID, strconv.Itoa(rank + 1)}
+				if addMinion {
+					labels = append([]string{minion}, labels...)
+				}
+				r.stateSlowestDurationSeconds.WithLabelValues(labels...).Set(state.Duration.Seconds())
+			}
+		}
+	}
+}
+
+// SetOrchestrationResults exposes the result and duration of the steps of the last orchestration run.
+func (r *Registry) SetOrchestrationResults(orchestration string, steps []event.StateResult, duration *time.Duration) {
+	// the previous run of the orchestration may have had other steps
+	previous := prometheus.Labels{"orchestration": orchestration}
+
+	if r.config.SaltOrchestrateStepResult.Enabled {
+		r.orchestrateStepResult.DeletePartialMatch(previous)
+		for _, step := range steps {
+			// skipped steps, i.e. test=True, have no result
+			if step.Result != nil {
+				r.orchestrateStepResult.WithLabelValues(orchestration, step.Name).Set(boolToFloat64(*step.Result))
+			}
+		}
+	}
+
+	if r.config.SaltOrchestrateStepDurationSeconds.Enabled {
+		r.orchestrateStepDurationSeconds.DeletePartialMatch(previous)
+		for _, step := range steps {
+			r.orchestrateStepDurationSeconds.WithLabelValues(orchestration, step.Name).Set(step.Duration.Seconds())
+		}
+	}
+
+	if r.config.SaltOrchestrateDurationSeconds.Enabled && duration != nil {
+		r.orchestrateDurationSeconds.WithLabelValues(orchestration).Set(duration.Seconds())
+	}
+}
+
+func (r *Registry) IncreaseWheelFunctionTotal(function string, success bool) {
+	if r.config.SaltWheelFunctionTotal.Enabled {
+		r.wheelFunctionTotal.WithLabelValues(function, strconv.FormatBool(success)).Inc()
+	}
+}
+
+// IncreaseReactorExecutionsTotal counts the salt/reactor events sent by the reactor SLS.
+//
+// Salt does not send any event when a reactor is executed, only the events sent by the user are counted.
+func (r *Registry) IncreaseReactorExecutionsTotal(sls string, success bool) {
+	if r.config.SaltReactorExecutionsTotal.Enabled {
+		r.reactorExecutionsTotal.WithLabelValues(sls, strconv.FormatBool(success)).Inc()
+	}
+}
+
+func (r *Registry) IncreaseKeyEventsTotal(action string) {
+	if r.config.SaltKeyEventsTotal.Enabled {
+		r.keyEventsTotal.WithLabelValues(action).Inc()
+	}
+}
+
+// seedKeys exposes all the key states, so the states without keys are 0 instead of missing.
+//
+// It is done on the first key, the sources without PKI watcher (i.e. salt-api) don't expose salt_keys.
+func (r *Registry) seedKeys() {
+	if r.keysSeeded {
+		return
+	}
+	for _, state := range []event.KeyState{event.KeyAccepted, event.KeyPending, event.KeyRejected, event.KeyDenied} {
+		r.keysTotal.WithLabelValues(state.String()).Set(0)
+	}
+	r.keysSeeded = true
+}
+
+// AddKey records the minion key.
+//
+// The keys are recorded even if salt_keys is disabled, the accepted keys are used to reconcile the presence.
+func (r *Registry) AddKey(minion string, state event.KeyState) {
+	if _, ok := r.keys[state]; !ok {
+		r.keys[state] = make(map[string]struct{})
+	}
+	r.keys[state][minion] = struct{}{}
+	r.updateKeysTotal(state)
+}
+
+func (r *Registry) DeleteKey(minion string, state event.KeyState) {
+	delete(r.keys[state], minion)
+	r.updateKeysTotal(state)
+}
+
+func (r *Registry) updateKeysTotal(state event.KeyState) {
+	if !r.config.SaltKeys.Enabled {
+		return
+	}
+	r.seedKeys()
+	r.keysTotal.WithLabelValues(state.String()).Set(float64(len(r.keys[state])))
+}
+
+func (r *Registry) IncreaseMinionAuthTotal(minion, result string) {
+	if !r.config.SaltMinionAuthTotal.Enabled {
+		return
+	}
+
+	labels := []string{result}
+	if r.config.SaltMinionAuthTotal.AddMinionLabel {
+		labels = append([]string{minion}, labels...)
+	}
+	r.minionAuthTotal.WithLabelValues(labels...).Inc()
+}
+
+func (r *Registry) UpdateMinionStart(minion string) {
+	if r.config.SaltMinionStartTotal.Enabled {
+		var labels []string
+		if r.config.SaltMinionStartTotal.AddMinionLabel {
+			labels = append(labels, minion)
+		}
+		r.minionStartTotal.WithLabelValues(labels...).Inc()
+	}
+	if r.config.SaltMinionLastStart.Enabled {
+		timestamp := time.Now().Unix()
+		r.minionLastStart.WithLabelValues(minion).Set(float64(timestamp))
+	}
+}
+
+// setMinionConnected updates the connection state of a minion.
+//
+// The connection/disconnection counters are only increased on state change,
+// or if the master explicitly notified the change.
+func (r *Registry) setMinionConnected(minion string, connected bool, notified bool) {
+	previous, known := r.connectedMinions[minion]
+	r.connectedMinions[minion] = connected
+
+	if r.config.SaltMinionConnected.Enabled {
+		r.minionConnected.WithLabelValues(minion).Set(boolToFloat64(connected))
+	}
+
+	if (known && previous == connected) || (!known && !notified) {
+		return
+	}
+
+	if connected && r.config.SaltMinionConnectTotal.Enabled {
+		var labels []string
+		if r.config.SaltMinionConnectTotal.AddMinionLabel {
+			labels = append(labels, minion)
+		}
+		r.minionConnectTotal.WithLabelValues(labels...).Inc()
+	}
+
+	if !connected && r.config.SaltMinionDisconnectTotal.Enabled {
+		var labels []string
+		if r.config.SaltMinionDisconnectTotal.AddMinionLabel {
+			labels = append(labels, minion)
+		}
+		r.minionDisconnectTotal.WithLabelValues(labels...).Inc()
+	}
+}
+
+// UpdatePresence reconciles the minions connection with the full list of present minions.
+//
+// The accepted minions which are not present are considered disconnected.
+func (r *Registry) UpdatePresence(present []string) {
+	r.presenceReceived = true
+
+	presentMinions := make(map[string]struct{}, len(present))
+	for _, minion := range present {
+		presentMinions[minion] = struct{}{}
+		r.setMinionConnected(minion, true, false)
+	}
+
+	for minion := range r.keys[event.KeyAccepted] {
+		if _, ok := presentMinions[minion]; !ok {
+			r.setMinionConnected(minion, false, false)
+		}
+	}
+	for minion := range r.connectedMinions {
+		if _, ok := presentMinions[minion]; !ok {
+			r.setMinionConnected(minion, false, false)
+		}
+	}
+}
+
+// UpdatePresenceChange updates the minions connection with the newly connected and lost minions.
+func (r *Registry) UpdatePresenceChange(connected, lost []string) {
+	r.presenceReceived = true
+
+	for _, minion := range connected {
+		r.setMinionConnected(minion, true, true)
+	}
+	for _, minion := range lost {
+		r.setMinionConnected(minion, false, true)
+	}
+}
+
+// UpdateBeaconMetrics updates the metrics declared for the beacon.
+func (r *Registry) UpdateBeaconMetrics(beacon, minion string, data any) {
+	for _, b := range r.beaconGauges {
+		if b.config.Beacon == beacon {
+			b.update(minion, data)
+		}
+	}
+}
+
+// UpdateCustomEvent counts the custom event, and exposes its value if configured.
+func (r *Registry) UpdateCustomEvent(tag, minion string, data any) {
+	customEvent, ok := matchCustomEvent(tag, r.config.CustomEvents)
+	if !ok {
+		return
+	}
+
+	r.customEventsTotal.WithLabelValues(customEvent.Tag).Inc()
+
+	if customEvent.Value == "" {
+		return
+	}
+
+	values := lookupPath(data, strings.Split(customEvent.Value, "."))
+	if len(values) != 1 {
+		return
+	}
+	if value, ok := toFloat64(values[0].value); ok {
+		r.customEventValue.WithLabelValues(customEvent.Tag, minion).Set(value)
+	}
+}
+
+func (r *Registry) jobTrackingEnabled() bool {
+	return r.config.SaltJobMissingResponsesTotal.Enabled || r.config.SaltJobResponseLatencySeconds.Enabled
+}
+
+// TrackJob registers a new job to detect the targeted minions which never return.
+//
+// published is the time at which the job was published by the master, it can be zero if unknown.
+func (r *Registry) TrackJob(jid, function, state string, minions []string, published time.Time) {
+	if !r.jobTrackingEnabled() {
+		return
+	}
+	deadline := time.Now().Add(r.config.SaltJobMissingResponsesTotal.Timeout)
+	r.jobs.add(jid, function, state, minions, published, deadline)
+}
+
+// TrackJobResponse flags the minion as returned for the given job.
+//
+// If both the publication and the response times are known, the response latency is observed.
+func (r *Registry) TrackJobResponse(jid, minion string, returned time.Time) {
+	if !r.jobTrackingEnabled() {
+		return
+	}
+
+	job, ok := r.jobs.markReturned(jid, minion)
+	if !ok || !r.config.SaltJobResponseLatencySeconds.Enabled {
+		return
+	}
+	if job.started.IsZero() || returned.IsZero() || returned.Before(job.started) {
+		return
+	}
+
+	labels := []string{job.function, job.state}
+	if r.config.SaltJobResponseLatencySeconds.AddMinionLabel {
+		labels = append([]string{minion}, labels...)
+	}
+	r.jobResponseLatency.WithLabelValues(labels...).Observe(returned.Sub(job.started).Seconds())
+}
+
+// ExpireJobs forgets the jobs which reached the timeout.
+//
+// The missing responses counter is increased for each minion which did not return in time.
+func (r *Registry) ExpireJobs(now time.Time) {
+	if !r.jobTrackingEnabled() {
+		return
+	}
+
+	missing := r.jobs.expire(now)
+	if !r.config.SaltJobMissingResponsesTotal.Enabled {
+		return
+	}
+	for _, m := range missing {
+		r.jobMissingResponsesTotal.WithLabelValues(m.function, m.state, m.minion).Inc()
+	}
+}
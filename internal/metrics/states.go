@@ -0,0 +1,24 @@
+package metrics
+
+import (
+	"sort"
+
+	"github.com/kpetremann/salt-exporter/pkg/event"
+)
+
+// slowestStatesBySLS returns the top slowest states of each SLS, sorted from the slowest.
+func slowestStatesBySLS(states []event.StateResult, top int) map[string][]event.StateResult {
+	bySLS := make(map[string][]event.StateResult)
+	for _, state := range states {
+		bySLS[state.SLS] = append(bySLS[state.SLS], state)
+	}
+
+	for sls, slsStates := range bySLS {
+		sort.SliceStable(slsStates, func(i, j int) bool { return slsStates[i].Duration > slsStates[j].Duration })
+		if len(slsStates) > top {
+			bySLS[sls] = slsStates[:top]
+		}
+	}
+
+	return bySLS
+}
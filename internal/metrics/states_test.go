@@ -0,0 +1,152 @@
+package metrics
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/kpetremann/salt-exporter/pkg/event"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestSlowestStatesBySLS(t *testing.T) {
+	states := []event.StateResult{
+		{ID: "a", SLS: "nginx", Duration: 1 * time.Second},
+		{ID: "b", SLS: "nginx", Duration: 3 * time.Second},
+		{ID: "c", SLS: "nginx", Duration: 2 * time.Second},
+		{ID: "d", SLS: "users", Duration: 1 * time.Second},
+	}
+
+	want := map[string][]event.StateResult{
+		"nginx": {
+			{ID: "b", SLS: "nginx", Duration: 3 * time.Second},
+			{ID: "c", SLS: "nginx", Duration: 2 * time.Second},
+		},
+		"users": {
+			{ID: "d", SLS: "users", Duration: 1 * time.Second},
+		},
+	}
+
+	if diff := cmp.Diff(slowestStatesBySLS(states, 2), want); diff != "" {
+		t.Errorf("Mismatch:\n%s", diff)
+	}
+}
+
+func TestIncreaseStateResultTotalFilters(t *testing.T) {
+	states := []event.StateResult{
+		{ID: "nginx_pkg", SLS: "nginx", Result: new(true)},
+		{ID: "nginx_conf", SLS: "nginx", Result: new(true), Changed: true},
+		{ID: "users_admin", SLS: "users", Result: new(false)},
+	}
+
+	tests := []struct {
+		name    string
+		include []string
+		exclude []string
+		want    []string
+	}{
+		{name: "no filter", want: []string{"nginx_conf", "nginx_pkg", "users_admin"}},
+		{name: "include only", include: []string{"nginx_*"}, want: []string{"nginx_conf", "nginx_pkg"}},
+		{name: "exclude only", exclude: []string{"nginx_pkg"}, want: []string{"nginx_conf", "users_admin"}},
+		{name: "include and exclude", include: []string{"nginx_*"}, exclude: []string{"nginx_conf"}, want: []string{"nginx_pkg"}},
+	}
+
+	for _, test := range tests {
+		config := testConfig()
+		config.SaltStateResultTotal.Enabled = true
+		config.SaltStateResultTotal.Filters.Include = test.include
+		config.SaltStateResultTotal.Filters.Exclude = test.exclude
+
+		r, err := NewRegistry(config, prometheus.NewRegistry())
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		r.IncreaseStateResultTotal("node1", "state.apply", states)
+
+		var got []string
+		for _, state := range states {
+			counter := r.stateResultTotal.WithLabelValues("node1", state.SLS, state.ID, "state.apply", state.Outcome())
+			if testutil.ToFloat64(counter) == 1 {
+				got = append(got, state.ID)
+			}
+		}
+		sort.Strings(got)
+
+		if diff := cmp.Diff(test.want, got); diff != "" {
+			t.Errorf("%s: mismatch (-want +got):\n%s", test.name, diff)
+		}
+	}
+}
+
+func TestSetStateChanges(t *testing.T) {
+	config := testConfig()
+	config.SaltStateChanges.Enabled = true
+	config.SaltStateChangesTotal.Enabled = true
+
+	r, err := NewRegistry(config, prometheus.NewRegistry())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	r.SetStateChanges("node1", "state.apply", "nginx", true, 3)
+	r.SetStateChanges("node1", "state.apply", "nginx", false, 2)
+	r.SetStateChanges("node1", "state.apply", "nginx", false, 0)
+
+	tests := []struct {
+		name   string
+		metric prometheus.Collector
+		want   float64
+	}{
+		{name: "last test run", metric: r.stateChanges.WithLabelValues("node1", "state.apply", "nginx", "true"), want: 3},
+		{name: "last real run without changes", metric: r.stateChanges.WithLabelValues("node1", "state.apply", "nginx", "false"), want: 0},
+		{name: "total of test runs", metric: r.stateChangesTotal.WithLabelValues("state.apply", "nginx", "true"), want: 3},
+		{name: "total of real runs", metric: r.stateChangesTotal.WithLabelValues("state.apply", "nginx", "false"), want: 2},
+	}
+
+	for _, test := range tests {
+		if got := testutil.ToFloat64(test.metric); got != test.want {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestStateDurations(t *testing.T) {
+	config := testConfig()
+	config.SaltJobDurationSeconds.Enabled = true
+	config.SaltStateDurationSeconds.Enabled = true
+	config.SaltStateSlowestDurationSeconds.Enabled = true
+	config.SaltStateSlowestDurationSeconds.Top = 1
+
+	r, err := NewRegistry(config, prometheus.NewRegistry())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	duration := 1500 * time.Millisecond
+	ret := stateSlsEvent("ret")
+	ret.StateDuration = &duration
+	ret.StateResults = []event.StateResult{{ID: "nginx_pkg", SLS: "nginx", Result: new(true), Duration: duration}}
+	eventToMetrics(ret, r)
+
+	if got := testutil.ToFloat64(r.stateSlowestDurationSeconds.WithLabelValues("nginx", "nginx_pkg", "1")); got != 1.5 {
+		t.Errorf("salt_state_slowest_duration_seconds = %v, want 1.5", got)
+	}
+	// salt_job_duration_seconds keeps the Salt milliseconds for backward compatibility
+	if got := testutil.ToFloat64(r.jobDurationSeconds.WithLabelValues("state.sls", "test")); got != 1500 {
+		t.Errorf("salt_job_duration_seconds = %v, want 1500", got)
+	}
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(r.stateDurationSeconds)
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	histogram := families[0].GetMetric()[0].GetHistogram()
+	if histogram.GetSampleCount() != 1 || histogram.GetSampleSum() != 1.5 {
+		t.Errorf("salt_state_duration_seconds: got %d samples summing to %v, want 1 sample of 1.5",
+			histogram.GetSampleCount(), histogram.GetSampleSum())
+	}
+}
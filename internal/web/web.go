@@ -0,0 +1,208 @@
+// Package web secures the exporter HTTP server.
+//
+// The configuration file uses a subset of the Prometheus exporter-toolkit web configuration format:
+//
+//	tls_server_config:
+//	  cert_file: /path/to/cert
+//	  key_file: /path/to/key
+//	  client_auth_type: RequireAndVerifyClientCert
+//	  client_ca_file: /path/to/ca
+//	  min_version: TLS12
+//	basic_auth_users:
+//	  prometheus: $2y$10$...
+package web
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/crypto/bcrypt"
+	"gopkg.in/yaml.v3"
+)
+
+// dummyHash is compared to the password of unknown users, so they can't be guessed from the response time.
+var dummyHash = []byte("$2a$10$AOswoVs/Fp2ly.5ey52oa.1GLuOzHLpTCZupMDhebCpXXx.b4j7Mi")
+
+var clientAuthTypes = map[string]tls.ClientAuthType{
+	"NoClientCert":               tls.NoClientCert,
+	"RequestClientCert":          tls.RequestClientCert,
+	"RequireAnyClientCert":       tls.RequireAnyClientCert,
+	"VerifyClientCertIfGiven":    tls.VerifyClientCertIfGiven,
+	"RequireAndVerifyClientCert": tls.RequireAndVerifyClientCert,
+}
+
+var tlsVersions = map[string]uint16{
+	"TLS10": tls.VersionTLS10,
+	"TLS11": tls.VersionTLS11,
+	"TLS12": tls.VersionTLS12,
+	"TLS13": tls.VersionTLS13,
+}
+
+// TLSConfig is the server side TLS configuration.
+type TLSConfig struct {
+	CertFile       string `yaml:"cert_file"`
+	KeyFile        string `yaml:"key_file"`
+	ClientAuthType string `yaml:"client_auth_type"`
+	ClientCAFile   string `yaml:"client_ca_file"`
+	MinVersion     string `yaml:"min_version"`
+	MaxVersion     string `yaml:"max_version"`
+}
+
+// Config is the web configuration of the exporter.
+type Config struct {
+	TLSServerConfig *TLSConfig `yaml:"tls_server_config"`
+
+	// BasicAuthUsers maps the users to their bcrypt hashed password.
+	BasicAuthUsers map[string]string `yaml:"basic_auth_users"`
+}
+
+// LoadConfig reads a web configuration file.
+//
+// The unsupported settings are rejected instead of being silently ignored.
+func LoadConfig(path string) (Config, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("failed to read web config: %w", err)
+	}
+
+	var cfg Config
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&cfg); err != nil {
+		return Config{}, fmt.Errorf("invalid web config: %w", err)
+	}
+
+	// relative paths are relative to the web config file, as in exporter-toolkit
+	if cfg.TLSServerConfig != nil {
+		dir := filepath.Dir(path)
+		cfg.TLSServerConfig.CertFile = joinPath(dir, cfg.TLSServerConfig.CertFile)
+		cfg.TLSServerConfig.KeyFile = joinPath(dir, cfg.TLSServerConfig.KeyFile)
+		cfg.TLSServerConfig.ClientCAFile = joinPath(dir, cfg.TLSServerConfig.ClientCAFile)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+
+	return cfg, nil
+}
+
+func joinPath(dir, path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+// Validate checks the consistency of the configuration.
+func (c Config) Validate() error {
+	for user, hash := range c.BasicAuthUsers {
+		if _, err := bcrypt.Cost([]byte(hash)); err != nil {
+			return fmt.Errorf("invalid bcrypt hash for user %s: %w", user, err)
+		}
+	}
+
+	if c.TLSServerConfig == nil {
+		return nil
+	}
+
+	if c.TLSServerConfig.CertFile == "" {
+		return errors.New("TLS Certificate not specified")
+	}
+	if c.TLSServerConfig.KeyFile == "" {
+		return errors.New("TLS Private Key not specified")
+	}
+
+	clientAuth := c.TLSServerConfig.ClientAuthType
+	if _, ok := clientAuthTypes[clientAuth]; clientAuth != "" && !ok {
+		return fmt.Errorf("invalid client_auth_type: %s", clientAuth)
+	}
+	if c.TLSServerConfig.ClientCAFile == "" &&
+		(clientAuth == "VerifyClientCertIfGiven" || clientAuth == "RequireAndVerifyClientCert") {
+		return fmt.Errorf("client_ca_file required with client_auth_type %s", clientAuth)
+	}
+
+	for _, version := range []string{c.TLSServerConfig.MinVersion, c.TLSServerConfig.MaxVersion} {
+		if _, ok := tlsVersions[version]; version != "" && !ok {
+			return fmt.Errorf("invalid TLS version: %s", version)
+		}
+	}
+
+	return nil
+}
+
+// ServerConfig builds the TLS configuration of the HTTP server.
+//
+// The certificate and key are loaded by the server.
+func (c TLSConfig) ServerConfig() (*tls.Config, error) {
+	cfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ClientAuth: tls.NoClientCert,
+	}
+
+	if c.MinVersion != "" {
+		cfg.MinVersion = tlsVersions[c.MinVersion]
+	}
+	if c.MaxVersion != "" {
+		cfg.MaxVersion = tlsVersions[c.MaxVersion]
+	}
+
+	if c.ClientCAFile != "" {
+		pem, err := os.ReadFile(c.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client CA: %w", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in client CA %s", c.ClientCAFile)
+		}
+		cfg.ClientCAs = pool
+
+		// same default as exporter-toolkit when a CA is provided
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	if c.ClientAuthType != "" {
+		cfg.ClientAuth = clientAuthTypes[c.ClientAuthType]
+	}
+
+	return cfg, nil
+}
+
+// BasicAuth wraps the handler to require one of the configured users.
+//
+// The handler is returned as is if no user is configured.
+func (c Config) BasicAuth(next http.Handler) http.Handler {
+	if len(c.BasicAuthUsers) == 0 {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, ok := r.BasicAuth()
+		if ok && c.authenticate(user, password) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("WWW-Authenticate", `Basic realm="salt-exporter", charset="UTF-8"`)
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+	})
+}
+
+func (c Config) authenticate(user, password string) bool {
+	hash, known := c.BasicAuthUsers[user]
+	if !known {
+		hash = string(dummyHash)
+	}
+
+	valid := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+
+	return known && valid
+}
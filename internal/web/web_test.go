@@ -0,0 +1,251 @@
+package web
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// secretHash is the bcrypt hash of "secret".
+const secretHash = "$2a$04$Cq.ZB.JErMbjRIdEz.eKROanbFUy.BVxHAxQeRukERN5s9ptr9cC2"
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write %s: %v", name, err)
+	}
+
+	return path
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+
+	path := writeFile(t, dir, "web.yml", `
+tls_server_config:
+  cert_file: server.crt
+  key_file: /etc/exporter/server.key
+  client_ca_file: ca.crt
+basic_auth_users:
+  prometheus: `+secretHash+`
+`)
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if cfg.TLSServerConfig.CertFile != filepath.Join(dir, "server.crt") {
+		t.Errorf("cert_file not relative to the web config: %s", cfg.TLSServerConfig.CertFile)
+	}
+	if cfg.TLSServerConfig.KeyFile != "/etc/exporter/server.key" {
+		t.Errorf("absolute key_file modified: %s", cfg.TLSServerConfig.KeyFile)
+	}
+	if cfg.TLSServerConfig.ClientCAFile != filepath.Join(dir, "ca.crt") {
+		t.Errorf("client_ca_file not relative to the web config: %s", cfg.TLSServerConfig.ClientCAFile)
+	}
+	if cfg.BasicAuthUsers["prometheus"] != secretHash {
+		t.Errorf("unexpected basic auth users: %v", cfg.BasicAuthUsers)
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{
+			name:    "unsupported setting",
+			content: "http_server_config:\n  http2: false\n",
+		},
+		{
+			name:    "invalid hash",
+			content: "basic_auth_users:\n  prometheus: secret\n",
+		},
+		{
+			name:    "missing key",
+			content: "tls_server_config:\n  cert_file: server.crt\n",
+		},
+		{
+			name:    "invalid client auth type",
+			content: "tls_server_config:\n  cert_file: server.crt\n  key_file: server.key\n  client_auth_type: Always\n",
+		},
+		{
+			name:    "missing client CA",
+			content: "tls_server_config:\n  cert_file: server.crt\n  key_file: server.key\n  client_auth_type: RequireAndVerifyClientCert\n",
+		},
+		{
+			name:    "invalid TLS version",
+			content: "tls_server_config:\n  cert_file: server.crt\n  key_file: server.key\n  min_version: SSL3\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := writeFile(t, t.TempDir(), "web.yml", test.content)
+			if _, err := LoadConfig(path); err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+
+	if _, err := LoadConfig(filepath.Join(t.TempDir(), "missing.yml")); err == nil {
+		t.Errorf("expected an error for a missing file")
+	}
+}
+
+func TestBasicAuth(t *testing.T) {
+	cfg := Config{BasicAuthUsers: map[string]string{"prometheus": secretHash}}
+	handler := cfg.BasicAuth(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name     string
+		user     string
+		password string
+		noAuth   bool
+		want     int
+	}{
+		{name: "valid", user: "prometheus", password: "secret", want: http.StatusOK},
+		{name: "wrong password", user: "prometheus", password: "wrong", want: http.StatusUnauthorized},
+		{name: "unknown user", user: "admin", password: "secret", want: http.StatusUnauthorized},
+		{name: "no credentials", noAuth: true, want: http.StatusUnauthorized},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+			if !test.noAuth {
+				req.SetBasicAuth(test.user, test.password)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != test.want {
+				t.Errorf("status code = %d, want %d", rec.Code, test.want)
+			}
+			if test.want == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") == "" {
+				t.Errorf("missing WWW-Authenticate header")
+			}
+		})
+	}
+}
+
+func TestBasicAuthDisabled(t *testing.T) {
+	handler := Config{}.BasicAuth(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("status code = %d, want %d", rec.Code, http.StatusOK)
+	}
+}
+
+// newCertificate creates a certificate signed by the parent, self-signed if parent is nil.
+func newCertificate(t *testing.T, parent *tls.Certificate, isCA bool) (tls.Certificate, []byte) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: "salt-exporter-test"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  isCA,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+
+	signer, signerKey := template, any(key)
+	if parent != nil {
+		signer, signerKey = parent.Leaf, parent.PrivateKey
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("failed to parse certificate: %v", err)
+	}
+
+	cert := tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+	return cert, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func TestClientCertificate(t *testing.T) {
+	ca, caPEM := newCertificate(t, nil, true)
+	client, _ := newCertificate(t, &ca, false)
+	unknownCA, _ := newCertificate(t, nil, true)
+	unknownClient, _ := newCertificate(t, &unknownCA, false)
+
+	tlsConfig, err := TLSConfig{ClientCAFile: writeFile(t, t.TempDir(), "ca.crt", string(caPEM))}.ServerConfig()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if tlsConfig.ClientAuth != tls.RequireAndVerifyClientCert {
+		t.Errorf("client certificate must be required by default when a CA is provided")
+	}
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	server.TLS = tlsConfig
+	server.StartTLS()
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		certs   []tls.Certificate
+		wantErr bool
+	}{
+		{name: "trusted client", certs: []tls.Certificate{client}},
+		{name: "untrusted client", certs: []tls.Certificate{unknownClient}, wantErr: true},
+		{name: "no client certificate", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			transport := server.Client().Transport.(*http.Transport).Clone()
+			transport.TLSClientConfig.Certificates = test.certs
+			httpClient := http.Client{Transport: transport}
+
+			resp, err := httpClient.Get(server.URL)
+			if err == nil {
+				resp.Body.Close()
+			}
+			if (err != nil) != test.wantErr {
+				t.Errorf("error = %v, wantErr %v", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestServerConfigInvalidCA(t *testing.T) {
+	path := writeFile(t, t.TempDir(), "ca.crt", "not a certificate")
+	if _, err := (TLSConfig{ClientCAFile: path}).ServerConfig(); err == nil {
+		t.Errorf("expected an error")
+	}
+}
@@ -3,6 +3,8 @@ package event
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
+	"slices"
 	"strings"
 	"time"
 
@@ -10,6 +12,9 @@ import (
 	"gopkg.in/yaml.v3"
 )
 
+// timestampLayout is the layout of the "_stamp" field set by Salt.
+const timestampLayout = "2006-01-02T15:04:05.999999"
+
 type EventModule int
 
 type WatchOp uint32
@@ -19,19 +24,86 @@ const (
 	RunnerModule
 	JobModule
 	BeaconModule
+	KeyModule
+	AuthModule
+	MinionModule
+	PresenceModule
+	WheelModule
+	ReactorModule
+	// CustomModule is used for the events with a non-Salt tag, i.e. fired by event.send
+	CustomModule
 )
 
+func (m EventModule) String() string {
+	switch m {
+	case RunnerModule:
+		return "runner"
+	case JobModule:
+		return "job"
+	case BeaconModule:
+		return "beacon"
+	case KeyModule:
+		return "key"
+	case AuthModule:
+		return "auth"
+	case MinionModule:
+		return "minion"
+	case PresenceModule:
+		return "presence"
+	case WheelModule:
+		return "wheel"
+	case ReactorModule:
+		return "reactor"
+	case CustomModule:
+		return "custom"
+	case UnknownModule:
+		return "unknown"
+	default:
+		return "unknown"
+	}
+}
+
 const (
 	Accepted WatchOp = iota
 	Removed
 )
 
+// KeyState is the state of a minion key on the master.
+type KeyState uint32
+
+const (
+	KeyAccepted KeyState = iota
+	KeyPending
+	KeyRejected
+	KeyDenied
+)
+
+func (s KeyState) String() string {
+	switch s {
+	case KeyAccepted:
+		return "accepted"
+	case KeyPending:
+		return "pending"
+	case KeyRejected:
+		return "rejected"
+	case KeyDenied:
+		return "denied"
+	default:
+		return "unknown"
+	}
+}
+
+// WatchEvent is a change of the minion keys on the master.
+//
+// Op is Accepted when the key is added in the State directory, Removed when the key is deleted from it.
 type WatchEvent struct {
 	MinionName string
 	Op         WatchOp
+	State      KeyState
 }
 
 type EventData struct {
+	Act       string   `msgpack:"act"`
 	Arg       []any    `msgpack:"arg"`
 	Cmd       string   `msgpack:"cmd"`
 	Fun       string   `msgpack:"fun"`
@@ -39,9 +111,14 @@ type EventData struct {
 	ID        string   `msgpack:"id"`
 	Jid       string   `msgpack:"jid"`
 	JidStamp  string   `msgpack:"jid_stamp"`
+	Lost      []string `msgpack:"lost"`
 	Minions   []string `msgpack:"minions"`
 	Missing   []string `msgpack:"missing"`
+	New       []string `msgpack:"new"`
 	Out       string   `msgpack:"out"`
+	Payload   any      `msgpack:"data"`
+	Present   []string `msgpack:"present"`
+	Result    *bool    `msgpack:"result"`
 	Retcode   int      `msgpack:"retcode"`
 	Return    any      `msgpack:"return"`
 	Tgt       any      `msgpack:"tgt"`
@@ -49,7 +126,45 @@ type EventData struct {
 	Timestamp string   `msgpack:"_stamp"`
 	User      string   `msgpack:"user"`
 	Schedule  string   `msgpack:"schedule"`
+	SLS       string   `msgpack:"sls"`
 	Success   *bool    `msgpack:"success"`
+	Tag       string   `msgpack:"tag"`
+}
+
+// Possible outcomes of a state execution.
+const (
+	StateChanged   = "changed"
+	StateUnchanged = "unchanged"
+	StateFailed    = "failed"
+	StateSkipped   = "skipped"
+)
+
+// StateResult is the result of a single state from a state function return.
+type StateResult struct {
+	// ID is the state key, i.e. pkg_|-nginx_|-nginx_|-installed
+	ID string
+	// Name is the state declaration ID (__id__), i.e. nginx
+	Name string
+	// SLS is the SLS file declaring the state
+	SLS string
+	// Result is nil when the result is unknown (i.e. changes pending with test=True)
+	Result   *bool
+	Changed  bool
+	Duration time.Duration
+}
+
+// Outcome returns the outcome of the state execution (changed, unchanged, failed or skipped).
+func (s StateResult) Outcome() string {
+	switch {
+	case s.Result == nil:
+		return StateSkipped
+	case !*s.Result:
+		return StateFailed
+	case s.Changed:
+		return StateChanged
+	default:
+		return StateUnchanged
+	}
 }
 
 type SaltEvent struct {
@@ -64,6 +179,9 @@ type SaltEvent struct {
 	IsMock             bool
 	StateModuleSuccess *bool
 	StateDuration      *time.Duration
+	StateResults       []StateResult
+	// ReceivedAt is the time at which the event was read from the event bus
+	ReceivedAt time.Time
 }
 
 // RawToJSON converts raw body to JSON
@@ -99,6 +217,30 @@ func (e SaltEvent) RawToYAML() ([]byte, error) {
 	return yaml.Marshal(data)
 }
 
+// ParseTimestamp parses the "_stamp" field of the event.
+//
+// Salt stamps events in UTC without timezone. RFC3339 is also accepted.
+func (e *SaltEvent) ParseTimestamp() (time.Time, error) {
+	if t, err := time.Parse(timestampLayout, e.Data.Timestamp); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339Nano, e.Data.Timestamp)
+}
+
+// CountStateChanges returns the number of states with and without changes.
+//
+// With test=True, the changes are the pending changes.
+func (e *SaltEvent) CountStateChanges() (changed, unchanged int) {
+	for _, state := range e.StateResults {
+		if state.Changed {
+			changed++
+		} else {
+			unchanged++
+		}
+	}
+	return changed, unchanged
+}
+
 func GetEventModule(tag string) EventModule {
 	tagParts := strings.Split(tag, "/")
 	if len(tagParts) < 2 {
@@ -111,33 +253,87 @@ func GetEventModule(tag string) EventModule {
 		return JobModule
 	case "beacon":
 		return BeaconModule
+	case "key":
+		return KeyModule
+	case "auth":
+		return AuthModule
+	case "minion":
+		return MinionModule
+	case "presence":
+		return PresenceModule
+	case "wheel":
+		return WheelModule
+	case "reactor":
+		return ReactorModule
 	default:
 		return UnknownModule
 	}
 }
 
+// normalizeMods sorts and deduplicates the SLS of a comma-separated mods list.
+//
+// i.e. "b, a,b" becomes "a,b".
+func normalizeMods(mods string) string {
+	if !strings.Contains(mods, ",") {
+		return strings.TrimSpace(mods)
+	}
+
+	var sls []string
+	for _, s := range strings.Split(mods, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			sls = append(sls, s)
+		}
+	}
+	slices.Sort(sls)
+
+	return strings.Join(slices.Compact(sls), ",")
+}
+
 // extractStateFromArgs extracts embedded state info.
 func extractStateFromArgs(args any, key string) string {
-	// args only
-	if v, ok := args.(string); ok {
-		return v
-	}
+	var state string
 
+	switch v := args.(type) {
+	// args only
+	case string:
+		state = v
 	// kwargs
-	if v, ok := args.(map[string]any); ok {
-		if _, keyExists := v[key]; !keyExists {
-			return ""
-		}
-		if ret, isString := v[key].(string); isString {
-			return ret
+	case map[string]any:
+		switch mods := v[key].(type) {
+		case string:
+			state = mods
+		case []any:
+			// mods can also be a list of SLS
+			sls := make([]string, 0, len(mods))
+			for _, s := range mods {
+				sls = append(sls, fmt.Sprint(s))
+			}
+			state = strings.Join(sls, ",")
 		}
 	}
 
-	return ""
+	if key == "mods" {
+		return normalizeMods(state)
+	}
+	return state
+}
+
+// IsOrchestration returns true if the event is related to an orchestration runner.
+func (e *SaltEvent) IsOrchestration() bool {
+	return e.Data.Fun == "runner.state.orchestrate" || e.Data.Fun == "runner.state.orch"
 }
 
 // Extract state info from event.
 func (e *SaltEvent) ExtractState() string {
+	if e.IsOrchestration() {
+		if len(e.Data.Arg) > 0 {
+			return extractStateFromArgs(e.Data.Arg[0], "mods")
+		} else if len(e.Data.FunArgs) > 0 {
+			return extractStateFromArgs(e.Data.FunArgs[0], "mods")
+		}
+		return ""
+	}
+
 	switch e.Data.Fun {
 	case "state.sls", "state.apply":
 		switch {
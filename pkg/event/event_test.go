@@ -2,6 +2,7 @@ package event_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/kpetremann/salt-exporter/pkg/event"
 )
@@ -38,6 +39,13 @@ func TestExtractState(t *testing.T) {
 	stateSlsFunArgMap.Data.Arg = nil
 	stateSlsFunArgMap.Data.FunArgs = []any{map[string]any{"mods": "test", "dry_run": true}}
 
+	stateSlsMultiple := getNewStateEvent()
+	stateSlsMultiple.Data.Arg = []any{"webserver, common,webserver"}
+
+	stateSlsList := getNewStateEvent()
+	stateSlsList.Data.Arg = nil
+	stateSlsList.Data.FunArgs = []any{map[string]any{"mods": []any{"webserver", "common"}}}
+
 	stateApplyArg := getNewStateEvent()
 	stateApplyArg.Data.Fun = "state.apply"
 
@@ -49,6 +57,16 @@ func TestExtractState(t *testing.T) {
 	stateHighstate.Data.Fun = "state.highstate"
 	stateHighstate.Data.Arg = nil
 
+	orchestrate := getNewStateEvent()
+	orchestrate.Data.Fun = "runner.state.orchestrate"
+	orchestrate.Data.Arg = nil
+	orchestrate.Data.FunArgs = []any{"orch.deploy", map[string]any{"pillar": map[string]any{}}}
+
+	orchestrateKwargs := getNewStateEvent()
+	orchestrateKwargs.Data.Fun = "runner.state.orch"
+	orchestrateKwargs.Data.Arg = nil
+	orchestrateKwargs.Data.FunArgs = []any{map[string]any{"mods": "orch.deploy"}}
+
 	tests := []struct {
 		name  string
 		event event.SaltEvent
@@ -89,6 +107,26 @@ func TestExtractState(t *testing.T) {
 			event: stateHighstate,
 			want:  "highstate",
 		},
+		{
+			name:  "state.sls with multiple SLS",
+			event: stateSlsMultiple,
+			want:  "common,webserver",
+		},
+		{
+			name:  "state.sls with a list of SLS",
+			event: stateSlsList,
+			want:  "common,webserver",
+		},
+		{
+			name:  "orchestration",
+			event: orchestrate,
+			want:  "orch.deploy",
+		},
+		{
+			name:  "orchestration kwargs only",
+			event: orchestrateKwargs,
+			want:  "orch.deploy",
+		},
 	}
 
 	for _, test := range tests {
@@ -97,3 +135,91 @@ func TestExtractState(t *testing.T) {
 		}
 	}
 }
+
+func TestParseTimestamp(t *testing.T) {
+	tests := []struct {
+		name      string
+		timestamp string
+		want      time.Time
+		wantErr   bool
+	}{
+		{
+			name:      "salt timestamp",
+			timestamp: "2022-06-30T00:00:00.123456",
+			want:      time.Date(2022, 6, 30, 0, 0, 0, 123456000, time.UTC),
+		},
+		{
+			name:      "RFC3339 timestamp",
+			timestamp: "2022-06-30T00:00:00.123456+00:00",
+			want:      time.Date(2022, 6, 30, 0, 0, 0, 123456000, time.UTC),
+		},
+		{
+			name:      "empty timestamp",
+			timestamp: "",
+			wantErr:   true,
+		},
+	}
+
+	for _, test := range tests {
+		e := getNewStateEvent()
+		e.Data.Timestamp = test.timestamp
+
+		res, err := e.ParseTimestamp()
+		if (err != nil) != test.wantErr {
+			t.Errorf("Unexpected error for '%s': %v", test.name, err)
+		}
+		if !res.Equal(test.want) {
+			t.Errorf("Mismatch for '%s', wants '%s' got '%s' ", test.name, test.want, res)
+		}
+	}
+}
+
+func TestStateResultOutcome(t *testing.T) {
+	tests := []struct {
+		name  string
+		state event.StateResult
+		want  string
+	}{
+		{
+			name:  "changed",
+			state: event.StateResult{Result: new(true), Changed: true},
+			want:  event.StateChanged,
+		},
+		{
+			name:  "unchanged",
+			state: event.StateResult{Result: new(true)},
+			want:  event.StateUnchanged,
+		},
+		{
+			name:  "failed",
+			state: event.StateResult{Result: new(false), Changed: true},
+			want:  event.StateFailed,
+		},
+		{
+			name:  "skipped",
+			state: event.StateResult{Changed: true},
+			want:  event.StateSkipped,
+		},
+	}
+
+	for _, test := range tests {
+		if res := test.state.Outcome(); res != test.want {
+			t.Errorf("Mismatch for '%s', wants '%s' got '%s' ", test.name, test.want, res)
+		}
+	}
+}
+
+func TestCountStateChanges(t *testing.T) {
+	e := getNewStateEvent()
+	e.StateResults = []event.StateResult{
+		{ID: "a", Result: new(true), Changed: true},
+		{ID: "b", Result: new(true)},
+		{ID: "c", Result: new(false)},
+		{ID: "d", Changed: true},
+	}
+
+	changed, unchanged := e.CountStateChanges()
+	if changed != 2 || unchanged != 2 {
+		t.Errorf("Mismatch, wants 2 changed and 2 unchanged, got %d changed and %d unchanged", changed, unchanged)
+	}
+}
@@ -0,0 +1,99 @@
+// Package handler defines the handlers converting the Salt events to Prometheus metrics.
+//
+// The handlers are registered by name, so they can be selected through the configuration:
+//
+//	func init() {
+//		handler.Register("deployments", func() (handler.Handler, error) {
+//			return newDeploymentsHandler(), nil
+//		})
+//	}
+package handler
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/kpetremann/salt-exporter/pkg/event"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Handler updates its metrics from the Salt events.
+//
+// The events are handled sequentially, a handler does not need to be safe for concurrent Handle calls.
+// However, Collect can be called concurrently to Handle.
+type Handler interface {
+	prometheus.Collector
+	// Handle updates the metrics from the event.
+	Handle(e event.SaltEvent)
+}
+
+// WatchHandler is implemented by the handlers interested in the minion keys changes.
+type WatchHandler interface {
+	Handler
+	// HandleWatch updates the metrics from the minion key change.
+	HandleWatch(e event.WatchEvent)
+}
+
+// Factory creates a new handler.
+type Factory func() (Handler, error)
+
+var (
+	factoriesMu sync.RWMutex
+	factories   = make(map[string]Factory)
+)
+
+// Register makes a handler available by the provided name.
+//
+// It panics if Register is called twice with the same name or if factory is nil.
+func Register(name string, factory Factory) {
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+
+	if factory == nil {
+		panic("handler: Register factory is nil")
+	}
+	if _, dup := factories[name]; dup {
+		panic("handler: Register called twice for handler " + name)
+	}
+	factories[name] = factory
+}
+
+// Names returns a sorted list of the registered handlers.
+func Names() []string {
+	factoriesMu.RLock()
+	defer factoriesMu.RUnlock()
+
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// New creates the handlers by name, in the provided order, and registers them on the registerer.
+func New(names []string, registerer prometheus.Registerer) ([]Handler, error) {
+	factoriesMu.RLock()
+	defer factoriesMu.RUnlock()
+
+	handlers := make([]Handler, 0, len(names))
+	for _, name := range names {
+		factory, ok := factories[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown handler: %s", name)
+		}
+
+		h, err := factory()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create handler %s: %w", name, err)
+		}
+		if err := registerer.Register(h); err != nil {
+			return nil, fmt.Errorf("failed to register handler %s: %w", name, err)
+		}
+		handlers = append(handlers, h)
+	}
+
+	return handlers, nil
+}
@@ -0,0 +1,63 @@
+package handler_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/kpetremann/salt-exporter/pkg/event"
+	"github.com/kpetremann/salt-exporter/pkg/handler"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type countHandler struct {
+	prometheus.Counter
+}
+
+func (h countHandler) Handle(_ event.SaltEvent) {
+	h.Inc()
+}
+
+func newCountHandler() (handler.Handler, error) {
+	return countHandler{prometheus.NewCounter(prometheus.CounterOpts{Name: "test_events_total"})}, nil
+}
+
+func TestNew(t *testing.T) {
+	handler.Register("test_count", newCountHandler)
+	handler.Register("test_broken", func() (handler.Handler, error) {
+		return nil, errors.New("broken")
+	})
+
+	if !slices.Contains(handler.Names(), "test_count") {
+		t.Errorf("Registered handler not listed: %v", handler.Names())
+	}
+
+	handlers, err := handler.New([]string{"test_count"}, prometheus.NewRegistry())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(handlers) != 1 {
+		t.Fatalf("Expected 1 handler, got %d", len(handlers))
+	}
+
+	if _, err := handler.New([]string{"test_count", "unknown"}, prometheus.NewRegistry()); err == nil {
+		t.Errorf("Unknown handler should return an error")
+	}
+	if _, err := handler.New([]string{"test_broken"}, prometheus.NewRegistry()); err == nil {
+		t.Errorf("Factory error should be returned")
+	}
+	if _, err := handler.New([]string{"test_count", "test_count"}, prometheus.NewRegistry()); err == nil {
+		t.Errorf("Registration error should be returned")
+	}
+}
+
+func TestRegisterTwice(t *testing.T) {
+	handler.Register("test_twice", newCountHandler)
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Registering the same name twice should panic")
+		}
+	}()
+	handler.Register("test_twice", newCountHandler)
+}
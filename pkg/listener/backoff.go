@@ -0,0 +1,112 @@
+package listener
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+)
+
+// ErrMaxRetries is returned when the connection is still failing after the maximum number of retries.
+var ErrMaxRetries = errors.New("maximum number of retries reached")
+
+// Backoff configures the delay between the connection attempts.
+//
+// The delay starts at InitialInterval and is multiplied by Multiplier after each failure, up to MaxInterval.
+// Jitter randomizes the delay by +/- this ratio, so several exporters don't retry at the same time.
+type Backoff struct {
+	InitialInterval time.Duration `mapstructure:"initial-interval"`
+	MaxInterval     time.Duration `mapstructure:"max-interval"`
+	Multiplier      float64       `mapstructure:"multiplier"`
+	Jitter          float64       `mapstructure:"jitter"`
+
+	// MaxRetries is the number of retries before giving up, unlimited if 0
+	MaxRetries int `mapstructure:"max-retries"`
+}
+
+// DefaultBackoff retries forever, from every second up to every minute.
+var DefaultBackoff = Backoff{
+	InitialInterval: time.Second,
+	MaxInterval:     time.Minute,
+	Multiplier:      2,
+	Jitter:          0.2,
+	MaxRetries:      0,
+}
+
+// Validate checks the backoff settings.
+func (b Backoff) Validate() error {
+	switch {
+	case b.InitialInterval <= 0:
+		return errors.New("initial-interval must be greater than 0")
+	case b.MaxInterval < b.InitialInterval:
+		return errors.New("max-interval must be greater than initial-interval")
+	case b.Multiplier < 1:
+		return errors.New("multiplier must be greater than or equal to 1")
+	case b.Jitter < 0 || b.Jitter > 1:
+		return errors.New("jitter must be between 0 and 1")
+	case b.MaxRetries < 0:
+		return errors.New("max-retries must be positive")
+	}
+	return nil
+}
+
+// delay returns the delay before the given retry, counted from 0: the first retry waits InitialInterval.
+func (b Backoff) delay(retry int) time.Duration {
+	d := float64(b.InitialInterval)
+	for range retry {
+		d *= b.Multiplier
+		if d >= float64(b.MaxInterval) {
+			d = float64(b.MaxInterval)
+			break
+		}
+	}
+
+	if b.Jitter > 0 {
+		d *= 1 + b.Jitter*(2*rand.Float64()-1) //nolint:gosec // no need for a secure random for the jitter
+	}
+
+	return time.Duration(d)
+}
+
+// retry calls connect until it succeeds.
+//
+// onRetry is called before waiting for the next attempt.
+// The error of the context is returned if it is cancelled, ErrMaxRetries if the maximum number of retries is reached.
+func (b Backoff) retry(
+	ctx context.Context, connect func() error, onRetry func(retry int, delay time.Duration, err error),
+) error {
+	for retry := 0; ; retry++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		err := connect()
+		if err == nil {
+			return nil
+		}
+
+		if b.MaxRetries > 0 && retry >= b.MaxRetries {
+			return fmt.Errorf("%w (%d): %w", ErrMaxRetries, b.MaxRetries, err)
+		}
+
+		delay := b.delay(retry)
+		onRetry(retry+1, delay, err)
+		if !sleep(ctx, delay) {
+			return ctx.Err()
+		}
+	}
+}
+
+// sleep waits for the delay, or until the context is cancelled in which case false is returned.
+func sleep(ctx context.Context, delay time.Duration) bool {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
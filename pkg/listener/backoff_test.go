@@ -0,0 +1,153 @@
+package listener
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kpetremann/salt-exporter/pkg/event"
+)
+
+var fastBackoff = Backoff{
+	InitialInterval: time.Millisecond,
+	MaxInterval:     5 * time.Millisecond,
+	Multiplier:      2,
+	MaxRetries:      3,
+}
+
+func TestBackoffDelay(t *testing.T) {
+	b := Backoff{InitialInterval: time.Second, MaxInterval: 10 * time.Second, Multiplier: 2}
+
+	want := []time.Duration{
+		time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second, 10 * time.Second,
+	}
+	for retry, w := range want {
+		if got := b.delay(retry); got != w {
+			t.Errorf("delay(%d) = %s, want %s", retry, got, w)
+		}
+	}
+
+	b.Jitter = 0.5
+	for range 100 {
+		if got := b.delay(1); got < time.Second || got > 3*time.Second {
+			t.Errorf("delay(1) = %s, want between 1s and 3s", got)
+		}
+	}
+}
+
+func TestBackoffValidate(t *testing.T) {
+	if err := DefaultBackoff.Validate(); err != nil {
+		t.Errorf("Unexpected error for the default backoff: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(b *Backoff)
+	}{
+		{name: "no initial interval", modify: func(b *Backoff) { b.InitialInterval = 0 }},
+		{name: "max lower than initial", modify: func(b *Backoff) { b.MaxInterval = time.Millisecond }},
+		{name: "decreasing", modify: func(b *Backoff) { b.Multiplier = 0.5 }},
+		{name: "jitter too high", modify: func(b *Backoff) { b.Jitter = 2 }},
+		{name: "negative retries", modify: func(b *Backoff) { b.MaxRetries = -1 }},
+	}
+
+	for _, test := range tests {
+		b := DefaultBackoff
+		test.modify(&b)
+		if err := b.Validate(); err == nil {
+			t.Errorf("%s: expected an error", test.name)
+		}
+	}
+}
+
+func TestBackoffRetry(t *testing.T) {
+	attempts := 0
+	var retries []int
+	connect := func() error {
+		attempts++
+		if attempts < 3 {
+			return errors.New("connection refused")
+		}
+		return nil
+	}
+	onRetry := func(retry int, _ time.Duration, _ error) {
+		retries = append(retries, retry)
+	}
+
+	if err := fastBackoff.retry(context.Background(), connect, onRetry); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if attempts != 3 || len(retries) != 2 || retries[1] != 2 {
+		t.Errorf("unexpected attempts: %d, retries: %v", attempts, retries)
+	}
+
+	failing := func() error { return errors.New("connection refused") }
+	err := fastBackoff.retry(context.Background(), failing, func(int, time.Duration, error) {})
+	if !errors.Is(err, ErrMaxRetries) {
+		t.Errorf("retry() = %v, want %v", err, ErrMaxRetries)
+	}
+}
+
+func TestBackoffRetryCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	slow := Backoff{InitialInterval: time.Hour, MaxInterval: time.Hour, Multiplier: 1}
+
+	done := make(chan error)
+	go func() {
+		done <- slow.retry(ctx, func() error { return errors.New("connection refused") }, func(int, time.Duration, error) {})
+	}()
+	cancel()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("retry() = %v, want %v", err, context.Canceled)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("the wait is not interrupted by the context cancellation")
+	}
+}
+
+// retryObserver counts the retries, the other notifications are ignored.
+type retryObserver struct {
+	noopObserver
+	retries int
+}
+
+func (o *retryObserver) BusRetrying(int, time.Duration) {
+	o.retries++
+}
+
+func TestListenEventsMaxRetries(t *testing.T) {
+	observer := &retryObserver{}
+
+	eventListener := NewEventListener(context.Background(), fakeParser{}, nil)
+	eventListener.SetIPCFilepath(filepath.Join(t.TempDir(), "missing.ipc"))
+	eventListener.SetBackoff(fastBackoff)
+	// the observers not implementing RetryObserver are skipped
+	eventListener.SetObserver(MultiObserver(noopObserver{}, observer))
+
+	if err := eventListener.ListenEvents(); !errors.Is(err, ErrMaxRetries) {
+		t.Errorf("ListenEvents() = %v, want %v", err, ErrMaxRetries)
+	}
+	if observer.retries != fastBackoff.MaxRetries {
+		t.Errorf("retries = %d, want %d", observer.retries, fastBackoff.MaxRetries)
+	}
+}
+
+func TestStartWatchingMaxRetries(t *testing.T) {
+	watcher, err := NewPKIWatcher(context.Background(), t.TempDir(), make(chan event.WatchEvent))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	watcher.SetBackoff(fastBackoff)
+
+	if err := watcher.StartWatching(); !errors.Is(err, ErrMaxRetries) {
+		t.Errorf("StartWatching() = %v, want %v", err, ErrMaxRetries)
+	}
+	if watcher.Initialized() {
+		t.Errorf("watcher must not be initialized")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"net"
+	"strings"
 	"time"
 
 	"github.com/kpetremann/salt-exporter/pkg/event"
@@ -17,6 +18,109 @@ type eventParser interface {
 
 const DefaultIPCFilepath = "/var/run/salt/master/master_event_pub.ipc"
 
+const tcpPrefix = "tcp://"
+
+// busAddress returns the network and address to dial for the event bus.
+//
+// The masters with `ipc_mode: tcp` publish the events on tcp://host:port,
+// otherwise the address is the path to the unix socket.
+func busAddress(address string) (string, string) {
+	if addr, ok := strings.CutPrefix(address, tcpPrefix); ok {
+		return "tcp", addr
+	}
+	return "unix", address
+}
+
+// Observer is notified of the listener activity, i.e. to expose metrics about the listener itself.
+type Observer interface {
+	// BusConnected is called when the connection to the event bus is opened or lost.
+	BusConnected(connected bool)
+	// BusReconnecting is called before each reconnection to the event bus.
+	BusReconnecting()
+	// EventParsed is called for each successfully parsed event.
+	EventParsed(e event.SaltEvent)
+	// ParseFailed is called for each message which can't be parsed.
+	ParseFailed(err error)
+}
+
+// RetryObserver can be implemented by an Observer to be notified of the connection retries.
+type RetryObserver interface {
+	// BusRetrying is called after each failed connection attempt, before waiting for the delay.
+	BusRetrying(retry int, delay time.Duration)
+}
+
+// busRetrying notifies the observer if it implements RetryObserver.
+func busRetrying(observer Observer, retry int, delay time.Duration) {
+	if o, ok := observer.(RetryObserver); ok {
+		o.BusRetrying(retry, delay)
+	}
+}
+
+// DropObserver can be implemented by an Observer to be notified of the events dropped by the overflow policy.
+type DropObserver interface {
+	// EventsDropped is called when events are dropped because the event channel is full.
+	EventsDropped(count int)
+}
+
+// eventsDropped notifies the observer if it implements DropObserver.
+func eventsDropped(observer Observer, count int) {
+	if o, ok := observer.(DropObserver); ok {
+		o.EventsDropped(count)
+	}
+}
+
+type noopObserver struct{}
+
+func (noopObserver) BusConnected(bool)           {}
+func (noopObserver) BusReconnecting()            {}
+func (noopObserver) EventParsed(event.SaltEvent) {}
+func (noopObserver) ParseFailed(error)           {}
+
+type multiObserver []Observer
+
+// MultiObserver returns an observer forwarding the notifications to all the given observers.
+func MultiObserver(observers ...Observer) Observer {
+	return multiObserver(observers)
+}
+
+func (m multiObserver) BusConnected(connected bool) {
+	for _, o := range m {
+		o.BusConnected(connected)
+	}
+}
+
+func (m multiObserver) BusReconnecting() {
+	for _, o := range m {
+		o.BusReconnecting()
+	}
+}
+
+// BusRetrying implements RetryObserver.
+func (m multiObserver) BusRetrying(retry int, delay time.Duration) {
+	for _, o := range m {
+		busRetrying(o, retry, delay)
+	}
+}
+
+func (m multiObserver) EventParsed(e event.SaltEvent) {
+	for _, o := range m {
+		o.EventParsed(e)
+	}
+}
+
+// EventsDropped implements DropObserver.
+func (m multiObserver) EventsDropped(count int) {
+	for _, o := range m {
+		eventsDropped(o, count)
+	}
+}
+
+func (m multiObserver) ParseFailed(err error) {
+	for _, o := range m {
+		o.ParseFailed(err)
+	}
+}
+
 // EventListener listens to the salt-master event bus and sends events to the event channel.
 type EventListener struct {
 	// ctx specificies the context used mainly for cancellation
@@ -25,7 +129,7 @@ type EventListener struct {
 	// eventChan is the channel to send events to
 	eventChan chan event.SaltEvent
 
-	// iPCFilepath is filepath to the salt-master event bus
+	// iPCFilepath is filepath to the salt-master event bus, or tcp://host:port
 	iPCFilepath string
 
 	// saltEventBus keeps the connection to the salt-master event bus
@@ -35,35 +139,48 @@ type EventListener struct {
 	decoder *msgpack.Decoder
 
 	eventParser eventParser
+
+	// observer is notified of the listener activity
+	observer Observer
+
+	// backoff configures the delay between the connection attempts
+	backoff Backoff
+
+	// overflowPolicy defines what happens when the event channel is full
+	overflowPolicy OverflowPolicy
 }
 
 // Open opens the salt-master event bus.
-func (e *EventListener) Open() {
-	log.Info().Str("file", e.iPCFilepath).Msg("connecting to salt-master event bus")
-	var err error
+//
+// The connection is retried until it succeeds, the context is cancelled or the maximum number of retries is reached.
+func (e *EventListener) Open() error {
+	log.Info().Str("address", e.iPCFilepath).Msg("connecting to salt-master event bus")
+	network, address := busAddress(e.iPCFilepath)
 
-	for {
-		select {
-		case <-e.ctx.Done():
-			return
-		default:
-		}
+	connect := func() error {
+		var err error
+		e.saltEventBus, err = net.Dial(network, address)
+		return err
+	}
+	onRetry := func(retry int, delay time.Duration, err error) {
+		log.Error().Err(err).Int("retry", retry).Msgf("failed to connect to event bus, retrying in %s", delay.Round(time.Millisecond))
+		busRetrying(e.observer, retry, delay)
+	}
 
-		e.saltEventBus, err = net.Dial("unix", e.iPCFilepath)
-		if err != nil {
-			log.Error().Msg("failed to connect to event bus, retrying in 5 seconds")
-			time.Sleep(time.Second * 5)
-		} else {
-			log.Info().Msg("successfully connected to event bus")
-			e.decoder = msgpack.NewDecoder(e.saltEventBus)
-			return
-		}
+	if err := e.backoff.retry(e.ctx, connect, onRetry); err != nil {
+		return err
 	}
+
+	log.Info().Msg("successfully connected to event bus")
+	e.decoder = msgpack.NewDecoder(e.saltEventBus)
+	e.observer.BusConnected(true)
+	return nil
 }
 
 // Close closes the salt-master event bus.
 func (e *EventListener) Close() error {
 	log.Info().Msg("disconnecting from salt-master event bus")
+	e.observer.BusConnected(false)
 	if e.saltEventBus != nil {
 		return e.saltEventBus.Close()
 	} else {
@@ -72,14 +189,14 @@ func (e *EventListener) Close() error {
 }
 
 // Reconnect reconnects to the salt-master event bus.
-func (e *EventListener) Reconnect() {
-	select {
-	case <-e.ctx.Done():
-		return
-	default:
-		e.Close()
-		e.Open()
+func (e *EventListener) Reconnect() error {
+	if err := e.ctx.Err(); err != nil {
+		return err
 	}
+
+	e.observer.BusReconnecting()
+	e.Close()
+	return e.Open()
 }
 
 // NewEventListener creates a new EventListener
@@ -91,13 +208,41 @@ func NewEventListener(ctx context.Context, eventParser eventParser, eventChan ch
 		eventChan:   eventChan,
 		eventParser: eventParser,
 		iPCFilepath: DefaultIPCFilepath,
+		observer:    noopObserver{},
+		backoff:     DefaultBackoff,
+
+		overflowPolicy: OverflowBlock,
 	}
 	return &e
 }
 
+// SetObserver sets the observer notified of the listener activity.
+func (e *EventListener) SetObserver(observer Observer) {
+	e.observer = observer
+}
+
+// SetBackoff sets the delay between the connection attempts.
+//
+// Default: DefaultBackoff.
+func (e *EventListener) SetBackoff(backoff Backoff) {
+	e.backoff = backoff
+}
+
+// SetOverflowPolicy sets what happens when the event channel is full.
+//
+// The event channel must be buffered to drop events.
+// An unknown policy, or OverflowDropOldest with an unbuffered channel, falls back to OverflowBlock.
+//
+// Default: OverflowBlock.
+func (e *EventListener) SetOverflowPolicy(policy OverflowPolicy) {
+	e.overflowPolicy = policy
+}
+
 // SetIPCFilepath sets the filepath to the salt-master event bus
 //
 // The IPC file must be readable by the user running the exporter.
+// The masters configured with `ipc_mode: tcp` are reached with tcp://host:port,
+// using the port set by `tcp_master_pub_port` (4512 by default).
 //
 // Default: /var/run/salt/master/master_event_pub.ipc.
 func (e *EventListener) SetIPCFilepath(filepath string) {
@@ -105,28 +250,64 @@ func (e *EventListener) SetIPCFilepath(filepath string) {
 }
 
 // ListenEvents listens to the salt-master event bus and sends events to the event channel.
-func (e *EventListener) ListenEvents() {
-	e.Open()
+//
+// It returns nil when the context is cancelled, or an error if the event bus can't be reached anymore.
+func (e *EventListener) ListenEvents() error {
+	if err := e.Open(); err != nil {
+		return e.stopError(err)
+	}
 
 	for {
 		select {
 		case <-e.ctx.Done():
 			log.Info().Msg("stop listening events")
 			e.Close()
-			return
+			return nil
 		default:
 			message, err := e.decoder.DecodeMap()
 			if err != nil {
 				log.Error().Str("error", err.Error()).Msg("unable to read event")
 				log.Error().Msg("event bus may be closed, trying to reconnect")
 
-				e.Reconnect()
+				if err := e.Reconnect(); err != nil {
+					return e.stopError(err)
+				}
 
 				continue
 			}
-			if event, err := e.eventParser.Parse(message); err == nil {
-				e.eventChan <- event
-			}
+
+			dispatch(message, time.Now(), e.eventParser, e.observer, e.eventChan, e.overflowPolicy)
 		}
 	}
 }
+
+// stopError returns nil if the listener is stopped because the context is cancelled.
+func (e *EventListener) stopError(err error) error {
+	if e.ctx.Err() != nil {
+		log.Info().Msg("stop listening events")
+		return nil
+	}
+	return err
+}
+
+// dispatch parses a message from the event bus and sends the event to the event channel.
+func dispatch(
+	message map[string]any,
+	receivedAt time.Time,
+	eventParser eventParser,
+	observer Observer,
+	eventChan chan event.SaltEvent,
+	policy OverflowPolicy,
+) {
+	event, err := eventParser.Parse(message)
+	if err != nil {
+		observer.ParseFailed(err)
+		return
+	}
+	event.ReceivedAt = receivedAt
+	observer.EventParsed(event)
+
+	if dropped := send(eventChan, event, policy); dropped > 0 {
+		eventsDropped(observer, dropped)
+	}
+}
@@ -0,0 +1,93 @@
+package listener
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/kpetremann/salt-exporter/pkg/event"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+type fakeParser struct{}
+
+func (fakeParser) Parse(message map[string]any) (event.SaltEvent, error) {
+	tag, _ := message["tag"].(string)
+	return event.SaltEvent{Tag: tag}, nil
+}
+
+func TestBusAddress(t *testing.T) {
+	tests := []struct {
+		address     string
+		wantNetwork string
+		wantAddress string
+	}{
+		{address: DefaultIPCFilepath, wantNetwork: "unix", wantAddress: DefaultIPCFilepath},
+		{address: "tcp://127.0.0.1:4512", wantNetwork: "tcp", wantAddress: "127.0.0.1:4512"},
+		{address: "tcp://[::1]:4512", wantNetwork: "tcp", wantAddress: "[::1]:4512"},
+	}
+
+	for _, test := range tests {
+		network, address := busAddress(test.address)
+		if network != test.wantNetwork || address != test.wantAddress {
+			t.Errorf("busAddress(%s) = %s, %s, want %s, %s",
+				test.address, network, address, test.wantNetwork, test.wantAddress)
+		}
+	}
+}
+
+func publish(t *testing.T, conn net.Conn, tag string) {
+	t.Helper()
+
+	if err := msgpack.NewEncoder(conn).Encode(map[string]any{"tag": tag}); err != nil {
+		t.Fatalf("failed to publish event: %v", err)
+	}
+}
+
+func receive(t *testing.T, eventChan <-chan event.SaltEvent, want string) {
+	t.Helper()
+
+	select {
+	case e := <-eventChan:
+		if e.Tag != want {
+			t.Errorf("tag = %s, want %s", e.Tag, want)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatalf("timeout waiting for %s", want)
+	}
+}
+
+func TestListenEventsTCP(t *testing.T) {
+	server, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	eventChan := make(chan event.SaltEvent)
+	eventListener := NewEventListener(ctx, fakeParser{}, eventChan)
+	eventListener.SetIPCFilepath("tcp://" + server.Addr().String())
+	go eventListener.ListenEvents()
+
+	conn, err := server.Accept()
+	if err != nil {
+		t.Fatalf("failed to accept: %v", err)
+	}
+	publish(t, conn, "salt/job/1/new")
+	receive(t, eventChan, "salt/job/1/new")
+
+	// the listener reconnects when the master closes the connection
+	conn.Close()
+
+	conn, err = server.Accept()
+	if err != nil {
+		t.Fatalf("failed to accept: %v", err)
+	}
+	defer conn.Close()
+	publish(t, conn, "salt/job/1/ret/node1")
+	receive(t, eventChan, "salt/job/1/ret/node1")
+}
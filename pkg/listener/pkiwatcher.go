@@ -2,10 +2,12 @@ package listener
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"path"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
@@ -15,12 +17,32 @@ import (
 
 const DefaultPKIDirpath = "/etc/salt/pki/master"
 
+// PKIObserver is notified of the PKI watcher initialization, i.e. to expose metrics.
+type PKIObserver interface {
+	// PKIRetrying is called after each failed attempt to load the accepted keys, before waiting for the delay.
+	PKIRetrying(retry int, delay time.Duration)
+	// PKIInitialized is called once the keys are loaded and watched.
+	PKIInitialized()
+}
+
+type noopPKIObserver struct{}
+
+func (noopPKIObserver) PKIRetrying(int, time.Duration) {}
+func (noopPKIObserver) PKIInitialized()                {}
+
 type PKIWatcher struct {
 	ctx        context.Context
 	pkiDirPath string
 	watcher    *fsnotify.Watcher
 	eventChan  chan<- event.WatchEvent
 	lock       sync.RWMutex
+
+	// initialized is set once the existing keys are loaded and watched
+	initialized atomic.Bool
+
+	// backoff configures the delay between the attempts to load the accepted keys
+	backoff  Backoff
+	observer PKIObserver
 }
 
 func NewPKIWatcher(ctx context.Context, pkiDirPath string, eventChan chan event.WatchEvent) (*PKIWatcher, error) {
@@ -35,6 +57,8 @@ func NewPKIWatcher(ctx context.Context, pkiDirPath string, eventChan chan event.
 		watcher:    watcher,
 		eventChan:  eventChan,
 		lock:       sync.RWMutex{},
+		backoff:    DefaultBackoff,
+		observer:   noopPKIObserver{},
 	}
 
 	return w, nil
@@ -49,70 +73,130 @@ func (w *PKIWatcher) SetPKIDirectory(filepath string) {
 	w.pkiDirPath = filepath
 }
 
-func (w *PKIWatcher) open() {
-	for {
-		select {
-		case <-w.ctx.Done():
-			return
-		default:
-		}
+// SetBackoff sets the delay between the attempts to load the accepted keys.
+//
+// Default: DefaultBackoff.
+func (w *PKIWatcher) SetBackoff(backoff Backoff) {
+	w.backoff = backoff
+}
 
-		minionsDir := path.Join(w.pkiDirPath, "minions")
-
-		log.Info().Msg("loading currently accepted minions")
-		entries, err := os.ReadDir(minionsDir)
-		if err != nil {
-			log.Error().Str("error", err.Error()).Msg("failed to list PKI directory")
-			time.Sleep(5 * time.Second)
-		} else {
-			for _, e := range entries {
-				if !e.IsDir() {
-					w.eventChan <- event.WatchEvent{
-						MinionName: e.Name(),
-						Op:         event.Accepted,
-					}
-					log.Info().Msgf("minion %s loaded", e.Name())
-				}
-			}
+// SetObserver sets the observer notified of the PKI watcher initialization.
+func (w *PKIWatcher) SetObserver(observer PKIObserver) {
+	w.observer = observer
+}
+
+// keyDirectories maps the PKI subdirectories to the state of the minion keys they contain.
+var keyDirectories = map[string]event.KeyState{
+	"minions":          event.KeyAccepted,
+	"minions_pre":      event.KeyPending,
+	"minions_rejected": event.KeyRejected,
+	"minions_denied":   event.KeyDenied,
+}
 
-			// Add a path.
-			err = w.watcher.Add(minionsDir)
-			if err != nil {
-				log.Error().Str("error", err.Error()).Msg("failed to watch PKI directory")
-				time.Sleep(time.Second * 5)
-			} else {
-				return
+// loadKeys sends the keys currently in the directory and starts watching it.
+func (w *PKIWatcher) loadKeys(dir string, state event.KeyState) error {
+	keysDir := path.Join(w.pkiDirPath, dir)
+
+	entries, err := os.ReadDir(keysDir)
+	if err != nil {
+		return fmt.Errorf("failed to list PKI directory: %w", err)
+	}
+
+	for _, e := range entries {
+		if !e.IsDir() {
+			w.eventChan <- event.WatchEvent{
+				MinionName: e.Name(),
+				Op:         event.Accepted,
+				State:      state,
 			}
+			log.Debug().Msgf("minion %s %s key loaded", e.Name(), state)
 		}
 	}
+
+	if err := w.watcher.Add(keysDir); err != nil {
+		return fmt.Errorf("failed to watch PKI directory: %w", err)
+	}
+
+	return nil
 }
 
-func (w *PKIWatcher) StartWatching() {
-	w.open()
+func (w *PKIWatcher) open() error {
+	log.Info().Msg("loading currently accepted minions")
+
+	load := func() error {
+		return w.loadKeys("minions", event.KeyAccepted)
+	}
+	onRetry := func(retry int, delay time.Duration, err error) {
+		log.Error().Err(err).Int("retry", retry).Msgf("failed to load accepted minions, retrying in %s", delay.Round(time.Millisecond))
+		w.observer.PKIRetrying(retry, delay)
+	}
+
+	if err := w.backoff.retry(w.ctx, load, onRetry); err != nil {
+		return err
+	}
+
+	// the other keys are optional, the directories can be missing depending on the master configuration
+	for dir, state := range keyDirectories {
+		if state == event.KeyAccepted {
+			continue
+		}
+		if err := w.loadKeys(dir, state); err != nil {
+			log.Warn().Str("error", err.Error()).Msgf("%s keys will not be watched", state)
+		}
+	}
+
+	return nil
+}
+
+// Initialized returns true once the accepted keys are loaded and the PKI directory is watched.
+func (w *PKIWatcher) Initialized() bool {
+	return w.initialized.Load()
+}
+
+// StartWatching loads the current keys and sends their changes to the event channel.
+//
+// It returns nil when the context is cancelled, or an error if the accepted keys can't be loaded.
+func (w *PKIWatcher) StartWatching() error {
+	if err := w.open(); err != nil {
+		w.Stop()
+		if w.ctx.Err() != nil {
+			return nil
+		}
+		return err
+	}
+	w.initialized.Store(true)
+	w.observer.PKIInitialized()
 
 	for {
 		select {
 		case <-w.ctx.Done():
 			w.Stop()
-			return
+			return nil
 		case evt := <-w.watcher.Events:
 			minionName := path.Base(evt.Name)
 			if minionName == ".key_cache" || strings.HasPrefix(minionName, ".___atomic_write") {
 				continue
 			}
+			state, ok := keyDirectories[path.Base(path.Dir(evt.Name))]
+			if !ok {
+				continue
+			}
 			if evt.Op == fsnotify.Create {
 				w.eventChan <- event.WatchEvent{
 					MinionName: minionName,
 					Op:         event.Accepted,
+					State:      state,
 				}
-				log.Info().Msgf("minion %s accepted by master", minionName)
+				log.Info().Msgf("minion %s key added to %s keys", minionName, state)
 			}
-			if evt.Op == fsnotify.Remove {
+			// salt-key moves the keys from one directory to another
+			if evt.Op == fsnotify.Remove || evt.Op == fsnotify.Rename {
 				w.eventChan <- event.WatchEvent{
 					MinionName: minionName,
 					Op:         event.Removed,
+					State:      state,
 				}
-				log.Info().Msgf("minion %s removed from master", minionName)
+				log.Info().Msgf("minion %s key removed from %s keys", minionName, state)
 			}
 		case err := <-w.watcher.Errors:
 			log.Error().Str("error", err.Error()).Msg("fail processing watch event")
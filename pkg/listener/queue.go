@@ -0,0 +1,64 @@
+package listener
+
+import (
+	"fmt"
+
+	"github.com/kpetremann/salt-exporter/pkg/event"
+)
+
+// OverflowPolicy defines what happens when the event channel is full.
+type OverflowPolicy string
+
+const (
+	// OverflowBlock waits for the channel to have room, the event bus is not read meanwhile.
+	OverflowBlock OverflowPolicy = "block"
+	// OverflowDropOldest drops the oldest event of the channel to make room for the new one.
+	OverflowDropOldest OverflowPolicy = "drop-oldest"
+	// OverflowDropNewest drops the new event.
+	OverflowDropNewest OverflowPolicy = "drop-newest"
+)
+
+// Validate checks the policy is supported.
+func (p OverflowPolicy) Validate() error {
+	switch p {
+	case OverflowBlock, OverflowDropOldest, OverflowDropNewest:
+		return nil
+	default:
+		return fmt.Errorf("invalid overflow policy: %s", p)
+	}
+}
+
+// send sends the event to the channel following the overflow policy.
+//
+// It returns the number of dropped events.
+// An unknown policy blocks, and the oldest event can't be dropped from an unbuffered channel so it blocks as well.
+func send(eventChan chan event.SaltEvent, e event.SaltEvent, policy OverflowPolicy) int {
+	switch {
+	case policy == OverflowDropNewest:
+		select {
+		case eventChan <- e:
+			return 0
+		default:
+			return 1
+		}
+	case policy == OverflowDropOldest && cap(eventChan) > 0:
+		dropped := 0
+		for {
+			select {
+			case eventChan <- e:
+				return dropped
+			default:
+			}
+
+			// the consumer may have made room meanwhile, so the oldest event is only dropped if still there
+			select {
+			case <-eventChan:
+				dropped++
+			default:
+			}
+		}
+	default:
+		eventChan <- e
+		return 0
+	}
+}
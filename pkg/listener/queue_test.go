@@ -0,0 +1,142 @@
+package listener
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kpetremann/salt-exporter/pkg/event"
+)
+
+func TestOverflowPolicyValidate(t *testing.T) {
+	for _, policy := range []OverflowPolicy{OverflowBlock, OverflowDropOldest, OverflowDropNewest} {
+		if err := policy.Validate(); err != nil {
+			t.Errorf("Unexpected error for %s: %v", policy, err)
+		}
+	}
+
+	if err := OverflowPolicy("drop-all").Validate(); err == nil {
+		t.Errorf("expected an error for an unknown policy")
+	}
+}
+
+func TestSend(t *testing.T) {
+	tests := []struct {
+		policy      OverflowPolicy
+		wantDropped int
+		wantTags    []string
+	}{
+		{policy: OverflowDropNewest, wantDropped: 1, wantTags: []string{"first", "second"}},
+		{policy: OverflowDropOldest, wantDropped: 1, wantTags: []string{"second", "third"}},
+	}
+
+	for _, test := range tests {
+		t.Run(string(test.policy), func(t *testing.T) {
+			eventChan := make(chan event.SaltEvent, 2)
+
+			dropped := 0
+			for _, tag := range []string{"first", "second", "third"} {
+				dropped += send(eventChan, event.SaltEvent{Tag: tag}, test.policy)
+			}
+
+			if dropped != test.wantDropped {
+				t.Errorf("dropped = %d, want %d", dropped, test.wantDropped)
+			}
+			for _, want := range test.wantTags {
+				if got := (<-eventChan).Tag; got != want {
+					t.Errorf("tag = %s, want %s", got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestSendBlock(t *testing.T) {
+	eventChan := make(chan event.SaltEvent, 1)
+	if dropped := send(eventChan, event.SaltEvent{Tag: "first"}, OverflowBlock); dropped != 0 {
+		t.Errorf("dropped = %d, want 0", dropped)
+	}
+
+	done := make(chan int)
+	go func() {
+		done <- send(eventChan, event.SaltEvent{Tag: "second"}, OverflowBlock)
+	}()
+
+	select {
+	case <-done:
+		t.Fatalf("send must block while the channel is full")
+	default:
+	}
+
+	<-eventChan
+	if dropped := <-done; dropped != 0 {
+		t.Errorf("dropped = %d, want 0", dropped)
+	}
+	if got := (<-eventChan).Tag; got != "second" {
+		t.Errorf("tag = %s, want second", got)
+	}
+}
+
+func TestSendFallbackToBlock(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   OverflowPolicy
+		capacity int
+	}{
+		{name: "unknown policy", policy: "drop-all", capacity: 1},
+		{name: "empty policy", policy: "", capacity: 1},
+		{name: "drop oldest unbuffered", policy: OverflowDropOldest, capacity: 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			eventChan := make(chan event.SaltEvent, test.capacity)
+			for range test.capacity {
+				eventChan <- event.SaltEvent{Tag: "first"}
+			}
+
+			done := make(chan int)
+			go func() {
+				done <- send(eventChan, event.SaltEvent{Tag: "second"}, test.policy)
+			}()
+
+			select {
+			case <-done:
+				t.Fatalf("send must block while the channel is full")
+			case <-time.After(10 * time.Millisecond):
+			}
+
+			for range test.capacity {
+				<-eventChan
+			}
+			if got := (<-eventChan).Tag; got != "second" {
+				t.Errorf("tag = %s, want second", got)
+			}
+			if dropped := <-done; dropped != 0 {
+				t.Errorf("dropped = %d, want 0", dropped)
+			}
+		})
+	}
+}
+
+// dropObserver counts the dropped events, the other notifications are ignored.
+type dropObserver struct {
+	noopObserver
+	dropped int
+}
+
+func (o *dropObserver) EventsDropped(count int) {
+	o.dropped += count
+}
+
+func TestDispatchEventsDropped(t *testing.T) {
+	observer := &dropObserver{}
+	eventChan := make(chan event.SaltEvent, 1)
+
+	for range 3 {
+		dispatch(map[string]any{}, time.Now(), fakeParser{}, MultiObserver(noopObserver{}, observer), eventChan, OverflowDropNewest)
+	}
+
+	if observer.dropped != 2 {
+		t.Errorf("dropped = %d, want 2", observer.dropped)
+	}
+}
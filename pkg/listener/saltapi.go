@@ -0,0 +1,265 @@
+package listener
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/kpetremann/salt-exporter/pkg/event"
+	"github.com/kpetremann/salt-exporter/pkg/parser"
+	"github.com/rs/zerolog/log"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// maxSSEEventSize is the maximum size of an event in the salt-api stream, the job returns can be large.
+const maxSSEEventSize = 64 * 1024 * 1024
+
+var errInvalidToken = errors.New("salt-api rejected the token, it may be expired")
+
+// SaltAPIListener listens to the salt-api /events Server-Sent Events stream and sends events to the event channel.
+//
+// It allows to run the exporter on another host than the salt-master.
+// Only the rest_cherrypy salt-api is supported.
+type SaltAPIListener struct {
+	// ctx specificies the context used mainly for cancellation
+	ctx context.Context
+
+	// eventChan is the channel to send events to
+	eventChan chan event.SaltEvent
+
+	// url is the base URL of salt-api, i.e. https://salt-master:8000
+	url string
+
+	// token is the salt-api token sent in the X-Auth-Token header
+	token string
+
+	client *http.Client
+
+	eventParser eventParser
+
+	// observer is notified of the listener activity
+	observer Observer
+
+	// backoff configures the delay between the connection attempts
+	backoff Backoff
+
+	// overflowPolicy defines what happens when the event channel is full
+	overflowPolicy OverflowPolicy
+}
+
+// NewSaltAPIListener creates a new SaltAPIListener
+//
+// The events will be sent to eventChan.
+func NewSaltAPIListener(
+	ctx context.Context, eventParser eventParser, eventChan chan event.SaltEvent, url string, token string,
+) *SaltAPIListener {
+	s := SaltAPIListener{
+		ctx:         ctx,
+		eventChan:   eventChan,
+		url:         strings.TrimSuffix(url, "/"),
+		token:       token,
+		client:      &http.Client{},
+		eventParser: eventParser,
+		observer:    noopObserver{},
+		backoff:     DefaultBackoff,
+
+		overflowPolicy: OverflowBlock,
+	}
+	return &s
+}
+
+// SetObserver sets the observer notified of the listener activity.
+func (s *SaltAPIListener) SetObserver(observer Observer) {
+	s.observer = observer
+}
+
+// SetBackoff sets the delay between the connection attempts.
+//
+// Default: DefaultBackoff.
+func (s *SaltAPIListener) SetBackoff(backoff Backoff) {
+	s.backoff = backoff
+}
+
+// SetOverflowPolicy sets what happens when the event channel is full.
+//
+// The event channel must be buffered to drop events.
+// An unknown policy, or OverflowDropOldest with an unbuffered channel, falls back to OverflowBlock.
+//
+// Default: OverflowBlock.
+func (s *SaltAPIListener) SetOverflowPolicy(policy OverflowPolicy) {
+	s.overflowPolicy = policy
+}
+
+// SetHTTPClient sets the HTTP client used to connect to salt-api, i.e. to trust a specific CA.
+//
+// The client must not have a timeout, as the stream is never ending.
+func (s *SaltAPIListener) SetHTTPClient(client *http.Client) {
+	s.client = client
+}
+
+// ListenEvents listens to the salt-api event stream and sends events to the event channel.
+//
+// The stream is reopened if it is closed by salt-api.
+// It returns nil when the context is cancelled, or an error if salt-api can't be reached anymore.
+func (s *SaltAPIListener) ListenEvents() error {
+	log.Info().Str("url", s.url).Msg("connecting to salt-api event stream")
+
+	// the retries are counted from the last successful connection
+	retry := 0
+	for {
+		connected, err := s.stream()
+		if s.ctx.Err() != nil {
+			log.Info().Msg("stop listening events")
+			return nil
+		}
+
+		if connected {
+			retry = 0
+		}
+		if s.backoff.MaxRetries > 0 && retry >= s.backoff.MaxRetries {
+			return fmt.Errorf("%w (%d): %w", ErrMaxRetries, s.backoff.MaxRetries, err)
+		}
+
+		delay := s.backoff.delay(retry)
+		retry++
+		log.Error().Err(err).Int("retry", retry).Msgf("salt-api event stream closed, retrying in %s", delay.Round(time.Millisecond))
+		busRetrying(s.observer, retry, delay)
+
+		if !sleep(s.ctx, delay) {
+			log.Info().Msg("stop listening events")
+			return nil
+		}
+		s.observer.BusReconnecting()
+	}
+}
+
+// stream reads the events until the stream is closed.
+//
+// connected is true if the stream has been opened.
+func (s *SaltAPIListener) stream() (bool, error) {
+	req, err := http.NewRequestWithContext(s.ctx, http.MethodGet, s.url+"/events", nil)
+	if err != nil {
+		return false, fmt.Errorf("invalid salt-api request: %w", err)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("X-Auth-Token", s.token)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("failed to connect to salt-api: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized:
+		return false, errInvalidToken
+	case resp.StatusCode != http.StatusOK:
+		return false, fmt.Errorf("unexpected salt-api response: %s", resp.Status)
+	}
+
+	log.Info().Msg("successfully connected to salt-api event stream")
+	s.observer.BusConnected(true)
+	defer s.observer.BusConnected(false)
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxSSEEventSize)
+
+	var data bytes.Buffer
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		switch {
+		case line == "":
+			// an empty line ends the event
+			if data.Len() > 0 {
+				s.dispatch(data.Bytes())
+				data.Reset()
+			}
+		case strings.HasPrefix(line, "data:"):
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		default:
+			// the tag, retry and comment lines are not needed, the tag is also in the data
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return true, fmt.Errorf("failed to read salt-api event stream: %w", err)
+	}
+
+	return true, errors.New("salt-api event stream ended")
+}
+
+func (s *SaltAPIListener) dispatch(data []byte) {
+	receivedAt := time.Now()
+
+	message, err := saltAPIMessage(data)
+	if err != nil {
+		log.Warn().Str("error", err.Error()).Msg("decoding_failure")
+		s.observer.ParseFailed(fmt.Errorf("%w: %w", parser.ErrDecodingFailure, err))
+		return
+	}
+
+	dispatch(message, receivedAt, s.eventParser, s.observer, s.eventChan, s.overflowPolicy)
+}
+
+// saltAPIMessage converts a salt-api JSON event to the event bus message format.
+//
+// salt-api sends:
+//
+//	{"tag": "salt/job/20231009.../new", "data": {"jid": "20231009...", ...}}
+//
+// and the event bus message body is:
+//
+//	salt/job/20231009.../new\n\n<msgpack encoded data>
+func saltAPIMessage(data []byte) (map[string]any, error) {
+	var raw struct {
+		Tag  string `json:"tag"`
+		Data any    `json:"data"`
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&raw); err != nil {
+		return nil, fmt.Errorf("invalid salt-api event: %w", err)
+	}
+
+	body, err := msgpack.Marshal(jsonToMsgpack(raw.Data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode salt-api event: %w", err)
+	}
+
+	return map[string]any{"body": append([]byte(raw.Tag+"\n\n"), body...)}, nil
+}
+
+// jsonToMsgpack restores the integers lost in the JSON decoding, i.e. the retcode.
+func jsonToMsgpack(value any) any {
+	switch v := value.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]any:
+		for key, item := range v {
+			v[key] = jsonToMsgpack(item)
+		}
+		return v
+	case []any:
+		for i, item := range v {
+			v[i] = jsonToMsgpack(item)
+		}
+		return v
+	default:
+		return v
+	}
+}
@@ -0,0 +1,130 @@
+package listener
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/kpetremann/salt-exporter/pkg/event"
+	"github.com/kpetremann/salt-exporter/pkg/parser"
+)
+
+const fakeToken = "fake-token"
+
+// fakeSaltAPI sends the events like the rest_cherrypy /events endpoint.
+func fakeSaltAPI(t *testing.T, events []string) *httptest.Server {
+	t.Helper()
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/events" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Header.Get("X-Auth-Token") != fakeToken {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "retry: 400\n\n")
+		for _, e := range events {
+			fmt.Fprintf(w, "tag: unused\ndata: %s\n\n", e)
+		}
+		w.(http.Flusher).Flush()
+
+		<-r.Context().Done()
+	}))
+}
+
+func TestSaltAPIListener(t *testing.T) {
+	server := fakeSaltAPI(t, []string{
+		`{"tag": "salt/job/20231009134437475000/ret/node1", "data": {` +
+			`"_stamp": "2023-10-09T13:44:37.512345", "cmd": "_return", "fun": "test.ping", "fun_args": [], ` +
+			`"id": "node1", "jid": "20231009134437475000", "retcode": 0, "return": true, "success": true}}`,
+		`not a json event`,
+		`{"tag": "myorg/deploy/finished", "data": {"_stamp": "2023-10-09T14:02:11.456789", "cmd": "_minion_event", ` +
+			`"data": {"app": "web", "duration": 42.5}, "id": "node1", "tag": "myorg/deploy/finished"}}`,
+	})
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	eventParser := parser.NewEventParser(false)
+	eventParser.CustomTags = []string{"myorg/deploy/*"}
+	eventChan := make(chan event.SaltEvent)
+	saltAPIListener := NewSaltAPIListener(ctx, eventParser, eventChan, server.URL+"/", fakeToken)
+	go saltAPIListener.ListenEvents()
+
+	success := true
+	ret := <-eventChan
+	wantRet := event.SaltEvent{
+		Tag:    "salt/job/20231009134437475000/ret/node1",
+		Type:   "ret",
+		Module: event.JobModule,
+		Data: event.EventData{
+			Timestamp: "2023-10-09T13:44:37.512345",
+			Cmd:       "_return",
+			Fun:       "test.ping",
+			FunArgs:   []any{},
+			ID:        "node1",
+			Jid:       "20231009134437475000",
+			Retcode:   0,
+			Return:    true,
+			Success:   &success,
+		},
+		ReceivedAt: ret.ReceivedAt,
+	}
+	if diff := cmp.Diff(wantRet, ret); diff != "" {
+		t.Errorf("Mismatch for job return (-want +got):\n%s", diff)
+	}
+
+	custom := <-eventChan
+	if custom.Module != event.CustomModule {
+		t.Errorf("module = %s, want %s", custom.Module, event.CustomModule)
+	}
+	if diff := cmp.Diff(map[string]any{"app": "web", "duration": 42.5}, custom.Data.Payload); diff != "" {
+		t.Errorf("Mismatch for custom event payload (-want +got):\n%s", diff)
+	}
+}
+
+func TestSaltAPIListenerInvalidToken(t *testing.T) {
+	server := fakeSaltAPI(t, nil)
+	defer server.Close()
+
+	s := NewSaltAPIListener(context.Background(), fakeParser{}, nil, server.URL, "expired")
+	if _, err := s.stream(); err != errInvalidToken { //nolint:errorlint // sentinel returned as is
+		t.Errorf("stream() = %v, want %v", err, errInvalidToken)
+	}
+}
+
+func TestSaltAPIMessage(t *testing.T) {
+	message, err := saltAPIMessage([]byte(`{"tag": "salt/job/1/ret/node1", "data": {"retcode": 2, "list": [1, 1.5]}}`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	body, ok := message["body"].([]byte)
+	if !ok {
+		t.Fatalf("body must be bytes, got %T", message["body"])
+	}
+	tag, _, _ := strings.Cut(string(body), "\n\n")
+	if tag != "salt/job/1/ret/node1" {
+		t.Errorf("tag = %s, want salt/job/1/ret/node1", tag)
+	}
+
+	want := map[string]any{"retcode": int64(2), "list": []any{int64(1), 1.5}}
+	got := jsonToMsgpack(map[string]any{"retcode": json.Number("2"), "list": []any{json.Number("1"), json.Number("1.5")}})
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Mismatch (-want +got):\n%s", diff)
+	}
+
+	if _, err := saltAPIMessage([]byte(`{"tag": `)); err == nil {
+		t.Errorf("expected an error for an invalid event")
+	}
+}
@@ -33,6 +33,13 @@ var expectedBeacon = event.SaltEvent{
 		Timestamp: "2023-10-09T11:36:02.205686",
 		ID:        "host1.example.com",
 		Minions:   []string{},
+		Payload: map[string]any{
+			"loadavg": map[string]any{
+				"1-min":  0.35,
+				"5-min":  0.48,
+				"15-min": 0.26,
+			},
+		},
 	},
 	IsScheduleJob: false,
 }
@@ -43,6 +50,13 @@ func fakeBeaconEvent() []byte {
 		Timestamp: "2023-10-09T11:36:02.205686",
 		Minions:   []string{},
 		ID:        "host1.example.com",
+		Payload: map[string]any{
+			"loadavg": map[string]any{
+				"1-min":  0.35,
+				"5-min":  0.48,
+				"15-min": 0.26,
+			},
+		},
 	}
 
 	fakeBody, err := msgpack.Marshal(fake)
@@ -0,0 +1,59 @@
+package parser_test
+
+import (
+	"log"
+
+	"github.com/kpetremann/salt-exporter/pkg/event"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+/*
+	Fake custom event, sent with: salt-call event.send myorg/deploy/finished '{"app": "web", "duration": 42.5}'
+
+	myorg/deploy/finished	{
+		"_stamp": "2023-10-09T14:02:11.456789",
+		"cmd": "_minion_event",
+		"data": {
+			"app": "web",
+			"duration": 42.5
+		},
+		"id": "node1",
+		"tag": "myorg/deploy/finished"
+	}
+*/
+
+var expectedCustomEvent = event.SaltEvent{
+	Tag:          "myorg/deploy/finished",
+	Type:         "",
+	Module:       event.CustomModule,
+	TargetNumber: 0,
+	Data: event.EventData{
+		Timestamp: "2023-10-09T14:02:11.456789",
+		Cmd:       "_minion_event",
+		ID:        "node1",
+		Payload:   map[string]any{"app": "web", "duration": 42.5},
+		Tag:       "myorg/deploy/finished",
+	},
+	IsScheduleJob: false,
+}
+
+func fakeCustomEvent() []byte {
+	// Marshal the data using MsgPack
+	fake := FakeData{
+		Timestamp: "2023-10-09T14:02:11.456789",
+		Cmd:       "_minion_event",
+		ID:        "node1",
+		Payload:   map[string]any{"app": "web", "duration": 42.5},
+		Tag:       "myorg/deploy/finished",
+	}
+
+	fakeBody, err := msgpack.Marshal(fake)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	fakeMessage := []byte("myorg/deploy/finished\n\n")
+	fakeMessage = append(fakeMessage, fakeBody...)
+
+	return fakeMessage
+}
@@ -7,18 +7,26 @@ import (
 )
 
 type FakeData struct {
+	Act       string   `msgpack:"act"`
 	Arg       []any    `msgpack:"arg"`
 	Cmd       string   `msgpack:"cmd"`
 	Fun       string   `msgpack:"fun"`
 	FunArgs   []any    `msgpack:"fun_args"`
 	ID        string   `msgpack:"id"`
 	Jid       string   `msgpack:"jid"`
+	Lost      []string `msgpack:"lost"`
 	Minions   []string `msgpack:"minions"`
 	Missing   []string `msgpack:"missing"`
+	New       []string `msgpack:"new"`
+	Payload   any      `msgpack:"data"`
+	Present   []string `msgpack:"present"`
+	Result    *bool    `msgpack:"result"`
 	Retcode   int      `msgpack:"retcode"`
 	Return    any      `msgpack:"return"`
 	Schedule  string   `msgpack:"schedule"`
+	SLS       string   `msgpack:"sls"`
 	Success   *bool    `msgpack:"success"`
+	Tag       string   `msgpack:"tag"`
 	Tgt       any      `msgpack:"tgt"`
 	TgtType   string   `msgpack:"tgt_type"`
 	Timestamp string   `msgpack:"_stamp"`
@@ -0,0 +1,52 @@
+package parser_test
+
+import (
+	"log"
+
+	"github.com/kpetremann/salt-exporter/pkg/event"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+/*
+Fake key event, when a minion key is accepted
+
+	salt/key	{
+		"_stamp": "2023-10-09T11:36:02.205686",
+		"act": "accept",
+		"id": "node1",
+		"result": true
+	}
+*/
+var expectedKeyAccept = event.SaltEvent{
+	Tag:          "salt/key",
+	Type:         "accept",
+	Module:       event.KeyModule,
+	TargetNumber: 0,
+	Data: event.EventData{
+		Timestamp: "2023-10-09T11:36:02.205686",
+		Act:       "accept",
+		ID:        "node1",
+		Result:    new(true),
+	},
+	IsScheduleJob: false,
+}
+
+func fakeKeyAcceptEvent() []byte {
+	// Marshal the data using MsgPack
+	fake := FakeData{
+		Timestamp: "2023-10-09T11:36:02.205686",
+		Act:       "accept",
+		ID:        "node1",
+		Result:    new(true),
+	}
+
+	fakeBody, err := msgpack.Marshal(fake)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	fakeMessage := []byte("salt/key\n\n")
+	fakeMessage = append(fakeMessage, fakeBody...)
+
+	return fakeMessage
+}
@@ -0,0 +1,97 @@
+package parser_test
+
+import (
+	"log"
+
+	"github.com/kpetremann/salt-exporter/pkg/event"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+/*
+Fake auth event, when a minion with an unknown key authenticates
+
+	salt/auth	{
+		"_stamp": "2023-10-09T11:36:02.205686",
+		"act": "pend",
+		"id": "node1",
+		"pub": "-----BEGIN PUBLIC KEY-----...",
+		"result": true
+	}
+*/
+var expectedAuthPending = event.SaltEvent{
+	Tag:          "salt/auth",
+	Type:         "pend",
+	Module:       event.AuthModule,
+	TargetNumber: 0,
+	Data: event.EventData{
+		Timestamp: "2023-10-09T11:36:02.205686",
+		Act:       "pend",
+		ID:        "node1",
+		Result:    new(true),
+	},
+	IsScheduleJob: false,
+}
+
+func fakeAuthPendingEvent() []byte {
+	// Marshal the data using MsgPack
+	fake := FakeData{
+		Timestamp: "2023-10-09T11:36:02.205686",
+		Act:       "pend",
+		ID:        "node1",
+		Result:    new(true),
+	}
+
+	fakeBody, err := msgpack.Marshal(fake)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	fakeMessage := []byte("salt/auth\n\n")
+	fakeMessage = append(fakeMessage, fakeBody...)
+
+	return fakeMessage
+}
+
+/*
+Fake minion start event
+
+	salt/minion/node1/start	{
+		"_stamp": "2023-10-09T11:36:02.205686",
+		"cmd": "_minion_event",
+		"data": "Minion node1 started at Mon Oct  9 11:36:02 2023",
+		"id": "node1",
+		"pretag": null,
+		"tag": "salt/minion/node1/start"
+	}
+*/
+var expectedMinionStart = event.SaltEvent{
+	Tag:          "salt/minion/node1/start",
+	Type:         "start",
+	Module:       event.MinionModule,
+	TargetNumber: 0,
+	Data: event.EventData{
+		Timestamp: "2023-10-09T11:36:02.205686",
+		Cmd:       "_minion_event",
+		ID:        "node1",
+	},
+	IsScheduleJob: false,
+}
+
+func fakeMinionStartEvent() []byte {
+	// Marshal the data using MsgPack
+	fake := FakeData{
+		Timestamp: "2023-10-09T11:36:02.205686",
+		Cmd:       "_minion_event",
+		ID:        "node1",
+	}
+
+	fakeBody, err := msgpack.Marshal(fake)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	fakeMessage := []byte("salt/minion/node1/start\n\n")
+	fakeMessage = append(fakeMessage, fakeBody...)
+
+	return fakeMessage
+}
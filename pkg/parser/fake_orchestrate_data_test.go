@@ -0,0 +1,174 @@
+package parser_test
+
+import (
+	"log"
+	"time"
+
+	"github.com/kpetremann/salt-exporter/pkg/event"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+/*
+	Fake orchestration return
+
+	salt/run/20231009121512345678/ret	{
+		"_stamp": "2023-10-09T12:15:30.123456",
+		"fun": "runner.state.orchestrate",
+		"fun_args": [
+			"orch.deploy"
+		],
+		"jid": "20231009121512345678",
+		"return": {
+			"data": {
+				"master1_master": {
+					"salt_|-deploy_web_|-deploy_web_|-state": {
+						"__id__": "deploy_web",
+						"__run_num__": 0,
+						"__sls__": "orch.deploy",
+						"changes": {
+							"ret": {}
+						},
+						"comment": "States ran successfully. Updating node1.",
+						"duration": 12.5,
+						"name": "deploy_web",
+						"result": true,
+						"start_time": "12:15:17.623456"
+					},
+					"salt_|-restart_lb_|-restart_lb_|-function": {
+						"__id__": "restart_lb",
+						"__run_num__": 1,
+						"__sls__": "orch.deploy",
+						"changes": {},
+						"comment": "Run failed on minions: lb1",
+						"duration": 1.5,
+						"name": "service.restart",
+						"result": false,
+						"start_time": "12:15:30.123456"
+					}
+				}
+			},
+			"outputter": "highstate",
+			"retcode": 1
+		},
+		"success": false,
+		"user": "root"
+	}
+*/
+
+var expectedOrchestrateReturn = event.SaltEvent{
+	Tag:          "salt/run/20231009121512345678/ret",
+	Type:         "ret",
+	Module:       event.RunnerModule,
+	TargetNumber: 0,
+	Data: event.EventData{
+		Timestamp: "2023-10-09T12:15:30.123456",
+		Fun:       "runner.state.orchestrate",
+		FunArgs:   []any{"orch.deploy"},
+		ID:        "master",
+		Jid:       "20231009121512345678",
+		Return: map[string]any{
+			"data": map[string]any{
+				"master1_master": map[string]any{
+					"salt_|-deploy_web_|-deploy_web_|-state": map[string]any{
+						"__id__":      "deploy_web",
+						"__run_num__": int8(0),
+						"__sls__":     "orch.deploy",
+						"changes":     map[string]any{"ret": map[string]any{}},
+						"comment":     "States ran successfully. Updating node1.",
+						"duration":    12.5,
+						"name":        "deploy_web",
+						"result":      true,
+						"start_time":  "12:15:17.623456",
+					},
+					"salt_|-restart_lb_|-restart_lb_|-function": map[string]any{
+						"__id__":      "restart_lb",
+						"__run_num__": int8(1),
+						"__sls__":     "orch.deploy",
+						"changes":     map[string]any{},
+						"comment":     "Run failed on minions: lb1",
+						"duration":    1.5,
+						"name":        "service.restart",
+						"result":      false,
+						"start_time":  "12:15:30.123456",
+					},
+				},
+			},
+			"outputter": "highstate",
+			"retcode":   int8(1),
+		},
+		Success: new(false),
+		User:    "root",
+	},
+	IsScheduleJob:      false,
+	StateModuleSuccess: new(false),
+	StateDuration:      new(time.Duration((12.5 + 1.5) * float64(time.Millisecond))),
+	StateResults: []event.StateResult{
+		{
+			ID:       "salt_|-deploy_web_|-deploy_web_|-state",
+			Name:     "deploy_web",
+			SLS:      "orch.deploy",
+			Result:   new(true),
+			Changed:  true,
+			Duration: time.Duration(12.5 * float64(time.Millisecond)),
+		},
+		{
+			ID:       "salt_|-restart_lb_|-restart_lb_|-function",
+			Name:     "restart_lb",
+			SLS:      "orch.deploy",
+			Result:   new(false),
+			Duration: time.Duration(1.5 * float64(time.Millisecond)),
+		},
+	},
+}
+
+func fakeOrchestrateReturnEvent() []byte {
+	// Marshal the data using MsgPack
+	fake := FakeData{
+		Timestamp: "2023-10-09T12:15:30.123456",
+		Fun:       "runner.state.orchestrate",
+		FunArgs:   []any{"orch.deploy"},
+		Jid:       "20231009121512345678",
+		Return: map[string]any{
+			"data": map[string]any{
+				"master1_master": map[string]any{
+					"salt_|-deploy_web_|-deploy_web_|-state": map[string]any{
+						"__id__":      "deploy_web",
+						"__run_num__": 0,
+						"__sls__":     "orch.deploy",
+						"changes":     map[string]any{"ret": map[string]any{}},
+						"comment":     "States ran successfully. Updating node1.",
+						"duration":    12.5,
+						"name":        "deploy_web",
+						"result":      true,
+						"start_time":  "12:15:17.623456",
+					},
+					"salt_|-restart_lb_|-restart_lb_|-function": map[string]any{
+						"__id__":      "restart_lb",
+						"__run_num__": 1,
+						"__sls__":     "orch.deploy",
+						"changes":     map[string]any{},
+						"comment":     "Run failed on minions: lb1",
+						"duration":    1.5,
+						"name":        "service.restart",
+						"result":      false,
+						"start_time":  "12:15:30.123456",
+					},
+				},
+			},
+			"outputter": "highstate",
+			"retcode":   1,
+		},
+		Success: new(false),
+		User:    "root",
+	}
+
+	fakeBody, err := msgpack.Marshal(fake)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	fakeMessage := []byte("salt/run/20231009121512345678/ret\n\n")
+	fakeMessage = append(fakeMessage, fakeBody...)
+
+	return fakeMessage
+}
@@ -0,0 +1,94 @@
+package parser_test
+
+import (
+	"log"
+
+	"github.com/kpetremann/salt-exporter/pkg/event"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+/*
+Fake presence event, periodically sent with the connected minions
+
+	salt/presence/present	{
+		"_stamp": "2023-10-09T11:36:02.205686",
+		"present": [
+			"node1",
+			"node2"
+		]
+	}
+*/
+var expectedPresencePresent = event.SaltEvent{
+	Tag:          "salt/presence/present",
+	Type:         "present",
+	Module:       event.PresenceModule,
+	TargetNumber: 0,
+	Data: event.EventData{
+		Timestamp: "2023-10-09T11:36:02.205686",
+		Present:   []string{"node1", "node2"},
+	},
+	IsScheduleJob: false,
+}
+
+func fakePresencePresentEvent() []byte {
+	// Marshal the data using MsgPack
+	fake := FakeData{
+		Timestamp: "2023-10-09T11:36:02.205686",
+		Present:   []string{"node1", "node2"},
+	}
+
+	fakeBody, err := msgpack.Marshal(fake)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	fakeMessage := []byte("salt/presence/present\n\n")
+	fakeMessage = append(fakeMessage, fakeBody...)
+
+	return fakeMessage
+}
+
+/*
+Fake presence change event
+
+	salt/presence/change	{
+		"_stamp": "2023-10-09T11:36:02.205686",
+		"new": [
+			"node3"
+		],
+		"lost": [
+			"node2"
+		]
+	}
+*/
+var expectedPresenceChange = event.SaltEvent{
+	Tag:          "salt/presence/change",
+	Type:         "change",
+	Module:       event.PresenceModule,
+	TargetNumber: 0,
+	Data: event.EventData{
+		Timestamp: "2023-10-09T11:36:02.205686",
+		New:       []string{"node3"},
+		Lost:      []string{"node2"},
+	},
+	IsScheduleJob: false,
+}
+
+func fakePresenceChangeEvent() []byte {
+	// Marshal the data using MsgPack
+	fake := FakeData{
+		Timestamp: "2023-10-09T11:36:02.205686",
+		New:       []string{"node3"},
+		Lost:      []string{"node2"},
+	}
+
+	fakeBody, err := msgpack.Marshal(fake)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	fakeMessage := []byte("salt/presence/change\n\n")
+	fakeMessage = append(fakeMessage, fakeBody...)
+
+	return fakeMessage
+}
@@ -0,0 +1,53 @@
+package parser_test
+
+import (
+	"log"
+
+	"github.com/kpetremann/salt-exporter/pkg/event"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+/*
+	Fake reactor execution event
+
+	salt/reactor/fired	{
+		"_stamp": "2023-10-09T13:25:42.345678",
+		"sls": "/srv/reactor/highstate_on_start.sls",
+		"success": true,
+		"tag": "salt/minion/node1/start"
+	}
+*/
+
+var expectedReactorFired = event.SaltEvent{
+	Tag:          "salt/reactor/fired",
+	Type:         "fired",
+	Module:       event.ReactorModule,
+	TargetNumber: 0,
+	Data: event.EventData{
+		Timestamp: "2023-10-09T13:25:42.345678",
+		SLS:       "/srv/reactor/highstate_on_start.sls",
+		Success:   new(true),
+		Tag:       "salt/minion/node1/start",
+	},
+	IsScheduleJob: false,
+}
+
+func fakeReactorFiredEvent() []byte {
+	// Marshal the data using MsgPack
+	fake := FakeData{
+		Timestamp: "2023-10-09T13:25:42.345678",
+		SLS:       "/srv/reactor/highstate_on_start.sls",
+		Success:   new(true),
+		Tag:       "salt/minion/node1/start",
+	}
+
+	fakeBody, err := msgpack.Marshal(fake)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	fakeMessage := []byte("salt/reactor/fired\n\n")
+	fakeMessage = append(fakeMessage, fakeBody...)
+
+	return fakeMessage
+}
@@ -80,7 +80,16 @@ var expectedStateHighstateWithEnvReturn = event.SaltEvent{
 	IsTest:             false,
 	IsMock:             false,
 	StateModuleSuccess: new(true),
-	StateDuration:      new(time.Duration(14.258 * float64(time.Second))),
+	StateDuration:      new(time.Duration(14.258 * float64(time.Millisecond))),
+	StateResults: []event.StateResult{
+		{
+			ID:       "file_|-hostname_file_|-/etc/hostname_|-managed",
+			Name:     "hostname_file",
+			SLS:      "defaults",
+			Result:   new(true),
+			Duration: time.Duration(14.258 * float64(time.Millisecond)),
+		},
+	},
 }
 
 func fakeStateHighstateWithEnvReturnEvent() []byte {
@@ -253,7 +262,16 @@ var expectedStateSlsReturn = event.SaltEvent{
 	IsTest:             false,
 	IsMock:             false,
 	StateModuleSuccess: new(true),
-	StateDuration:      new(time.Duration(0.481 * float64(time.Second))),
+	StateDuration:      new(time.Duration(0.481 * float64(time.Millisecond))),
+	StateResults: []event.StateResult{
+		{
+			ID:       "test_|-dummy test_|-Dummy test_|-nop",
+			Name:     "dummy test",
+			SLS:      "test",
+			Result:   new(true),
+			Duration: time.Duration(0.481 * float64(time.Millisecond)),
+		},
+	},
 }
 
 func fakeStateSlsReturnEvent() []byte {
@@ -452,7 +470,15 @@ var expectedStateSingleReturn = event.SaltEvent{
 	IsTest:             false,
 	IsMock:             false,
 	StateModuleSuccess: new(true),
-	StateDuration:      new(time.Duration(0.49 * float64(time.Second))),
+	StateDuration:      new(time.Duration(0.49 * float64(time.Millisecond))),
+	StateResults: []event.StateResult{
+		{
+			ID:       "test_|-toto_|-toto_|-nop",
+			Name:     "toto",
+			Result:   new(true),
+			Duration: time.Duration(0.49 * float64(time.Millisecond)),
+		},
+	},
 }
 
 func fakeStateSingleReturnEvent() []byte {
@@ -683,7 +709,23 @@ var expectedTestMockStateSlsReturn = event.SaltEvent{
 	IsTest:             true,
 	IsMock:             true,
 	StateModuleSuccess: new(false),
-	StateDuration:      new(time.Duration((0.481 + 0.579) * float64(time.Second))),
+	StateDuration:      new(time.Duration((0.481 + 0.579) * float64(time.Millisecond))),
+	StateResults: []event.StateResult{
+		{
+			ID:       "somestate_|-dummy somestate_|-Dummy somestate_|-nop",
+			Name:     "dummy somestate",
+			SLS:      "somestate",
+			Result:   new(true),
+			Duration: time.Duration(0.481 * float64(time.Millisecond)),
+		},
+		{
+			ID:       "somestate_|-failed_|-failed_|-fail_with_changes",
+			Name:     "dummy somestate",
+			SLS:      "somestate",
+			Result:   new(false),
+			Duration: time.Duration(0.579 * float64(time.Millisecond)),
+		},
+	},
 }
 
 func fakeTestMockStateSlsReturnEvent() []byte {
@@ -0,0 +1,70 @@
+package parser_test
+
+import (
+	"log"
+
+	"github.com/kpetremann/salt-exporter/pkg/event"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+/*
+	Fake wheel return
+
+	salt/wheel/20231009132011123456/ret	{
+		"_stamp": "2023-10-09T13:20:11.234567",
+		"fun": "wheel.key.accept",
+		"fun_args": [
+			{
+				"match": "node1"
+			}
+		],
+		"jid": "20231009132011123456",
+		"return": {
+			"minions": [
+				"node1"
+			]
+		},
+		"success": true,
+		"user": "root"
+	}
+*/
+
+var expectedWheelReturn = event.SaltEvent{
+	Tag:          "salt/wheel/20231009132011123456/ret",
+	Type:         "ret",
+	Module:       event.WheelModule,
+	TargetNumber: 0,
+	Data: event.EventData{
+		Timestamp: "2023-10-09T13:20:11.234567",
+		Fun:       "wheel.key.accept",
+		FunArgs:   []any{map[string]any{"match": "node1"}},
+		Jid:       "20231009132011123456",
+		Return:    map[string]any{"minions": []any{"node1"}},
+		Success:   new(true),
+		User:      "root",
+	},
+	IsScheduleJob: false,
+}
+
+func fakeWheelReturnEvent() []byte {
+	// Marshal the data using MsgPack
+	fake := FakeData{
+		Timestamp: "2023-10-09T13:20:11.234567",
+		Fun:       "wheel.key.accept",
+		FunArgs:   []any{map[string]any{"match": "node1"}},
+		Jid:       "20231009132011123456",
+		Return:    map[string]any{"minions": []string{"node1"}},
+		Success:   new(true),
+		User:      "root",
+	}
+
+	fakeBody, err := msgpack.Marshal(fake)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	fakeMessage := []byte("salt/wheel/20231009132011123456/ret\n\n")
+	fakeMessage = append(fakeMessage, fakeBody...)
+
+	return fakeMessage
+}
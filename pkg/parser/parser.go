@@ -3,9 +3,11 @@ package parser
 import (
 	"errors"
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 
+	"github.com/kpetremann/salt-exporter/internal/filters"
 	"github.com/kpetremann/salt-exporter/pkg/event"
 	"github.com/rs/zerolog/log"
 	"github.com/vmihailenco/msgpack/v5"
@@ -14,8 +16,18 @@ import (
 const testArg = "test"
 const mockArg = "mock"
 
+// Errors returned by the parser.
+var (
+	ErrUnsupportedTag  = errors.New("tag not supported")
+	ErrUnknownModule   = errors.New("tag not supported. Module unknown")
+	ErrInvalidTag      = errors.New("invalid salt tag")
+	ErrDecodingFailure = errors.New("decoding failure")
+)
+
 type Event struct {
 	KeepRewBody bool
+	// CustomTags are the patterns of the non-Salt tags to parse, i.e. myorg/deploy/*
+	CustomTags []string
 }
 
 func NewEventParser(keepRawBody bool) Event {
@@ -64,8 +76,24 @@ func getBoolKwarg(event event.SaltEvent, field string) bool {
 	return false
 }
 
+// stateReturn returns the states of a state function return.
+//
+// For orchestrations, the steps are extracted from the runner return.
+func stateReturn(ev event.SaltEvent) (map[string]any, bool) {
+	substates, ok := ev.Data.Return.(map[string]any)
+	if !ok {
+		return nil, false
+	}
+
+	if ev.IsOrchestration() {
+		return orchestrationSteps(substates)
+	}
+
+	return substates, true
+}
+
 func statemoduleResult(event event.SaltEvent) *bool {
-	substates, ok := event.Data.Return.(map[string]any)
+	substates, ok := stateReturn(event)
 	if !ok {
 		return nil
 	}
@@ -95,9 +123,91 @@ func statemoduleResult(event event.SaltEvent) *bool {
 	return &success
 }
 
+// stateResults extracts the result of each state of a state function return.
+//
+// It returns nil if the return is not a state return.
+func stateResults(ev event.SaltEvent) []event.StateResult {
+	substates, ok := stateReturn(ev)
+	if !ok || len(substates) == 0 {
+		return nil
+	}
+
+	results := make([]event.StateResult, 0, len(substates))
+	for id, v := range substates {
+		substate, ok := v.(map[string]any)
+		if !ok {
+			return nil
+		}
+
+		result, ok := substate["result"]
+		if !ok {
+			return nil
+		}
+
+		state := event.StateResult{ID: id}
+		if r, ok := result.(bool); ok {
+			state.Result = &r
+		}
+		if name, ok := substate["__id__"].(string); ok {
+			state.Name = name
+		}
+		if sls, ok := substate["__sls__"].(string); ok {
+			state.SLS = sls
+		}
+		if changes, ok := substate["changes"].(map[string]any); ok {
+			state.Changed = len(changes) > 0
+		}
+		// Salt reports the duration in milliseconds
+		if duration, ok := substate["duration"].(float64); ok {
+			state.Duration = time.Duration(duration * float64(time.Millisecond))
+		}
+
+		results = append(results, state)
+	}
+
+	sort.Slice(results, func(i, j int) bool { return results[i].ID < results[j].ID })
+
+	return results
+}
+
+// orchestrationSteps extracts the steps of an orchestration return.
+//
+// The orchestration runner wraps the steps in the master ID:
+//
+//	"return": {
+//		"data": {
+//			"master_id": {
+//				"salt_|-deploy_|-deploy_|-state": {...}
+//			}
+//		},
+//		"outputter": "highstate",
+//		"retcode": 0
+//	}
+func orchestrationSteps(ret map[string]any) (map[string]any, bool) {
+	data, ok := ret["data"].(map[string]any)
+	if !ok {
+		return nil, false
+	}
+
+	steps := make(map[string]any)
+	for _, v := range data {
+		masterSteps, ok := v.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		for id, step := range masterSteps {
+			steps[id] = step
+		}
+	}
+
+	return steps, true
+}
+
 // StateDuration sums all inner duration.
+//
+// Salt reports the duration of each state in milliseconds.
 func stateDuration(event event.SaltEvent) *time.Duration {
-	substates, ok := event.Data.Return.(map[string]any)
+	substates, ok := stateReturn(event)
 	if !ok {
 		return nil
 	}
@@ -122,7 +232,7 @@ func stateDuration(event event.SaltEvent) *time.Duration {
 		return nil
 	}
 
-	d := time.Duration(totalDuration * float64(time.Second))
+	d := time.Duration(totalDuration * float64(time.Millisecond))
 	return &d
 }
 
@@ -138,28 +248,43 @@ func (e Event) Parse(message map[string]any) (event.SaltEvent, error) {
 	lines := strings.SplitN(body, "\n\n", 2)
 
 	tag := lines[0]
-	if !(strings.HasPrefix(tag, "salt/")) {
-		return event.SaltEvent{}, errors.New("tag not supported")
+	isCustom := !strings.HasPrefix(tag, "salt/")
+	if isCustom && !filters.Match(tag, e.CustomTags) {
+		return event.SaltEvent{}, ErrUnsupportedTag
 	}
 	log.Debug().Str("tag", tag).Msg("new event")
 
 	parts := strings.Split(tag, "/")
 
-	if len(parts) < 3 {
-		return event.SaltEvent{}, errors.New("tag not supported")
-	}
-
 	eventModule := event.GetEventModule(tag)
+	if isCustom {
+		eventModule = event.CustomModule
+	}
 
 	if eventModule == event.UnknownModule {
-		return event.SaltEvent{}, errors.New("tag not supported. Module unknown")
+		return event.SaltEvent{}, ErrUnknownModule
 	}
 
 	// Extract job type from the tag
-	if len(tag) < 4 {
-		return event.SaltEvent{}, fmt.Errorf("invalid salt tag: %s", tag)
+	//
+	// salt/key and salt/auth events have no type in the tag, the action is only in the body.
+	actionInBody := eventModule == event.KeyModule || eventModule == event.AuthModule
+
+	var jobType string
+	switch {
+	case actionInBody, eventModule == event.CustomModule:
+	case eventModule == event.PresenceModule || eventModule == event.ReactorModule:
+		// salt/presence/<type> or salt/reactor/<type>
+		if len(parts) < 3 {
+			return event.SaltEvent{}, fmt.Errorf("%w: %s", ErrInvalidTag, tag)
+		}
+		jobType = parts[2]
+	default:
+		if len(parts) < 4 {
+			return event.SaltEvent{}, fmt.Errorf("%w: %s", ErrInvalidTag, tag)
+		}
+		jobType = parts[3]
 	}
-	jobType := strings.Split(tag, "/")[3]
 
 	// Parse message body
 	byteResult := []byte(lines[1])
@@ -171,7 +296,19 @@ func (e Event) Parse(message map[string]any) (event.SaltEvent, error) {
 
 	if err := msgpack.Unmarshal(byteResult, &ev.Data); err != nil {
 		log.Warn().Str("error", err.Error()).Str("tag", tag).Msg("decoding_failure")
-		return event.SaltEvent{}, err
+		return event.SaltEvent{}, fmt.Errorf("%w: %w", ErrDecodingFailure, err)
+	}
+
+	if actionInBody {
+		ev.Type = ev.Data.Act
+	}
+
+	// only some beacons like status nest their fields under "data", most of them send the fields at the top level
+	if eventModule == event.BeaconModule && ev.Data.Payload == nil {
+		var payload map[string]any
+		if err := msgpack.Unmarshal(byteResult, &payload); err == nil {
+			ev.Data.Payload = payload
+		}
 	}
 
 	// Extract other info
@@ -181,6 +318,7 @@ func (e Event) Parse(message map[string]any) (event.SaltEvent, error) {
 	ev.IsMock = getBoolKwarg(ev, mockArg)
 	ev.StateModuleSuccess = statemoduleResult(ev)
 	ev.StateDuration = stateDuration(ev)
+	ev.StateResults = stateResults(ev)
 
 	// A runner are executed on the master but they do not provide their ID in the event
 	if strings.HasPrefix(tag, "salt/run") && ev.Data.ID == "" {
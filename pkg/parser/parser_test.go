@@ -1,7 +1,9 @@
 package parser_test
 
 import (
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/kpetremann/salt-exporter/pkg/event"
@@ -74,14 +76,60 @@ func TestParseEvent(t *testing.T) {
 			args: fakeEventAsMap(fakeStateHighstateWithEnvReturnEvent()),
 			want: expectedStateHighstateWithEnvReturn,
 		},
+		{
+			name: "return orchestration",
+			args: fakeEventAsMap(fakeOrchestrateReturnEvent()),
+			want: expectedOrchestrateReturn,
+		},
+		{
+			name: "return wheel",
+			args: fakeEventAsMap(fakeWheelReturnEvent()),
+			want: expectedWheelReturn,
+		},
+		{
+			name: "reactor fired",
+			args: fakeEventAsMap(fakeReactorFiredEvent()),
+			want: expectedReactorFired,
+		},
 		{
 			name: "beacon",
 			args: fakeEventAsMap(fakeBeaconEvent()),
 			want: expectedBeacon,
 		},
+		{
+			name: "key accepted",
+			args: fakeEventAsMap(fakeKeyAcceptEvent()),
+			want: expectedKeyAccept,
+		},
+		{
+			name: "auth pending",
+			args: fakeEventAsMap(fakeAuthPendingEvent()),
+			want: expectedAuthPending,
+		},
+		{
+			name: "minion start",
+			args: fakeEventAsMap(fakeMinionStartEvent()),
+			want: expectedMinionStart,
+		},
+		{
+			name: "presence present",
+			args: fakeEventAsMap(fakePresencePresentEvent()),
+			want: expectedPresencePresent,
+		},
+		{
+			name: "presence change",
+			args: fakeEventAsMap(fakePresenceChangeEvent()),
+			want: expectedPresenceChange,
+		},
+		{
+			name: "custom event",
+			args: fakeEventAsMap(fakeCustomEvent()),
+			want: expectedCustomEvent,
+		},
 	}
 
 	p := parser.NewEventParser(false)
+	p.CustomTags = []string{"myorg/deploy/*"}
 	for _, test := range tests {
 		parsed, err := p.Parse(test.args)
 		if err != nil {
@@ -93,3 +141,56 @@ func TestParseEvent(t *testing.T) {
 		}
 	}
 }
+
+func TestParseStateDuration(t *testing.T) {
+	// the highstate fixture has a single state taking "duration": 14.258, in milliseconds
+	ev, err := parser.NewEventParser(false).Parse(fakeEventAsMap(fakeStateHighstateWithEnvReturnEvent()))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	want := 14258 * time.Microsecond
+	if ev.StateDuration == nil || *ev.StateDuration != want {
+		t.Errorf("StateDuration = %v, want %s", ev.StateDuration, want)
+	}
+	if len(ev.StateResults) != 1 || ev.StateResults[0].Duration != want {
+		t.Errorf("StateResults = %v, want a state of %s", ev.StateResults, want)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		args map[string]any
+		want error
+	}{
+		{
+			name: "custom event not matching the custom tags",
+			args: fakeEventAsMap(fakeCustomEvent()),
+			want: parser.ErrUnsupportedTag,
+		},
+		{
+			name: "unknown module",
+			args: map[string]any{"body": "salt/unknown/20231009092021123456/new\n\n"},
+			want: parser.ErrUnknownModule,
+		},
+		{
+			name: "tag without type",
+			args: map[string]any{"body": "salt/job/20231009092021123456\n\n"},
+			want: parser.ErrInvalidTag,
+		},
+		{
+			name: "invalid body",
+			args: map[string]any{"body": "salt/job/20231009092021123456/new\n\n\xc1"},
+			want: parser.ErrDecodingFailure,
+		},
+	}
+
+	p := parser.NewEventParser(false)
+	p.CustomTags = []string{"myorg/build/*"}
+	for _, test := range tests {
+		if _, err := p.Parse(test.args); !errors.Is(err, test.want) {
+			t.Errorf("%s: got error %v, want %v", test.name, err, test.want)
+		}
+	}
+}